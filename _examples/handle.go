@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/fasthttp/session/v2"
 	"github.com/valyala/fasthttp"
 )
 
@@ -27,16 +28,7 @@ func indexHandler(ctx *fasthttp.RequestCtx) {
 
 // set handler
 func setHandler(ctx *fasthttp.RequestCtx) {
-	store, err := serverSession.Get(ctx)
-	if err != nil {
-		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		return
-	}
-	defer func() {
-		if err := serverSession.Save(ctx, store); err != nil {
-			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		}
-	}()
+	store := session.FromRequestCtx(ctx)
 
 	store.Set("foo", "bar")
 
@@ -45,16 +37,7 @@ func setHandler(ctx *fasthttp.RequestCtx) {
 
 // get handler
 func getHandler(ctx *fasthttp.RequestCtx) {
-	store, err := serverSession.Get(ctx)
-	if err != nil {
-		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		return
-	}
-	defer func() {
-		if err := serverSession.Save(ctx, store); err != nil {
-			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		}
-	}()
+	store := session.FromRequestCtx(ctx)
 
 	val := store.Get("foo")
 	if val == nil {
@@ -67,16 +50,7 @@ func getHandler(ctx *fasthttp.RequestCtx) {
 
 // delete handler
 func deleteHandler(ctx *fasthttp.RequestCtx) {
-	store, err := serverSession.Get(ctx)
-	if err != nil {
-		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		return
-	}
-	defer func() {
-		if err := serverSession.Save(ctx, store); err != nil {
-			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		}
-	}()
+	store := session.FromRequestCtx(ctx)
 
 	store.Delete("foo")
 
@@ -90,16 +64,7 @@ func deleteHandler(ctx *fasthttp.RequestCtx) {
 
 // get all handler
 func getAllHandler(ctx *fasthttp.RequestCtx) {
-	store, err := serverSession.Get(ctx)
-	if err != nil {
-		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		return
-	}
-	defer func() {
-		if err := serverSession.Save(ctx, store); err != nil {
-			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		}
-	}()
+	store := session.FromRequestCtx(ctx)
 
 	store.Set("foo1", "bar1")
 	store.Set("foo2", 2)
@@ -115,16 +80,7 @@ func getAllHandler(ctx *fasthttp.RequestCtx) {
 
 // flush handle
 func flushHandler(ctx *fasthttp.RequestCtx) {
-	store, err := serverSession.Get(ctx)
-	if err != nil {
-		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		return
-	}
-	defer func() {
-		if err := serverSession.Save(ctx, store); err != nil {
-			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		}
-	}()
+	store := session.FromRequestCtx(ctx)
 
 	store.Flush()
 
@@ -148,16 +104,7 @@ func destroyHandler(ctx *fasthttp.RequestCtx) {
 
 // get sessionID handle
 func sessionIDHandler(ctx *fasthttp.RequestCtx) {
-	store, err := serverSession.Get(ctx)
-	if err != nil {
-		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		return
-	}
-	defer func() {
-		if err := serverSession.Save(ctx, store); err != nil {
-			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		}
-	}()
+	store := session.FromRequestCtx(ctx)
 
 	sessionID := store.GetSessionID()
 	ctx.SetBodyString("Session: Current session id: ")
@@ -166,13 +113,9 @@ func sessionIDHandler(ctx *fasthttp.RequestCtx) {
 
 // regenerate handler
 func regenerateHandler(ctx *fasthttp.RequestCtx) {
-	if err := serverSession.Regenerate(ctx); err != nil {
-		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		return
-	}
+	store := session.FromRequestCtx(ctx)
 
-	store, err := serverSession.Get(ctx)
-	if err != nil {
+	if err := serverSession.RegenerateStore(ctx, store); err != nil {
 		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
 		return
 	}
@@ -183,11 +126,7 @@ func regenerateHandler(ctx *fasthttp.RequestCtx) {
 
 // get expiration handler
 func getExpirationHandler(ctx *fasthttp.RequestCtx) {
-	store, err := serverSession.Get(ctx)
-	if err != nil {
-		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		return
-	}
+	store := session.FromRequestCtx(ctx)
 
 	expiration := store.GetExpiration()
 
@@ -197,19 +136,9 @@ func getExpirationHandler(ctx *fasthttp.RequestCtx) {
 
 // set expiration handler
 func setExpirationHandler(ctx *fasthttp.RequestCtx) {
-	store, err := serverSession.Get(ctx)
-	if err != nil {
-		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		return
-	}
-	defer func() {
-		if err := serverSession.Save(ctx, store); err != nil {
-			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
-		}
-	}()
+	store := session.FromRequestCtx(ctx)
 
-	err = store.SetExpiration(30 * time.Second)
-	if err != nil {
+	if err := store.SetExpiration(30 * time.Second); err != nil {
 		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
 		return
 	}
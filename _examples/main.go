@@ -7,6 +7,7 @@ import (
 
 	"github.com/fasthttp/router"
 	"github.com/fasthttp/session/v2"
+	"github.com/fasthttp/session/v2/providers/cookie"
 	"github.com/fasthttp/session/v2/providers/memcache"
 	"github.com/fasthttp/session/v2/providers/memory"
 	"github.com/fasthttp/session/v2/providers/mongodb"
@@ -32,6 +33,15 @@ func init() {
 	var err error
 
 	switch *providerName {
+	case "cookie":
+		encoder = session.MSGPEncode
+		decoder = session.MSGPDecode
+		provider, err = cookie.New(cookie.Config{
+			CookieName: "sessiondata",
+			Keys: [][]byte{
+				[]byte("0123456789abcdef0123456789abcdef"),
+			},
+		})
 	case "memory":
 		encoder = session.MSGPEncode
 		decoder = session.MSGPDecode
@@ -104,7 +114,7 @@ func main() {
 	addr := "0.0.0.0:8086"
 	log.Println("Session example server listen: http://" + addr)
 
-	err := fasthttp.ListenAndServe(addr, r.Handler)
+	err := fasthttp.ListenAndServe(addr, serverSession.Middleware(r.Handler))
 	if err != nil {
 		log.Fatal(err)
 	}
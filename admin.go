@@ -0,0 +1,317 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// AdminOptions configuration of the admin handler
+type AdminOptions struct {
+	// Authorize should return whether the given request is allowed to use the admin handler.
+	//
+	// If nil, all the requests are rejected with a forbidden response,
+	// so the sessions are never exposed by mistake.
+	Authorize func(*fasthttp.RequestCtx) bool
+
+	// Prefix is the path where the admin handler is mounted, e.g. "/admin/sessions".
+	// It's removed from the request path before routing.
+	Prefix string
+
+	// RedactKeys are the keys of the session values which are never shown,
+	// their values are replaced by "[REDACTED]"
+	RedactKeys []string
+
+	// RedactFunc is called with each session value which is not redacted by RedactKeys,
+	// and should return the value to show.
+	//
+	// If nil, the values are shown as they are.
+	RedactFunc func(key string, value interface{}) interface{}
+}
+
+type adminSessionInfo struct {
+	ID         string     `json:"id"`
+	Size       int        `json:"size"`
+	LastActive *time.Time `json:"lastActive,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
+
+type adminSessionList struct {
+	Sessions []adminSessionInfo `json:"sessions"`
+	Next     string             `json:"next,omitempty"`
+}
+
+type adminSession struct {
+	ID         string                 `json:"id"`
+	CreatedAt  *time.Time             `json:"createdAt,omitempty"`
+	LastAccess *time.Time             `json:"lastAccess,omitempty"`
+	UserID     string                 `json:"userId,omitempty"`
+	Version    uint64                 `json:"version,omitempty"`
+	Values     map[string]interface{} `json:"values"`
+}
+
+type adminStats struct {
+	Count   int    `json:"count"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+type adminError struct {
+	Error string `json:"error"`
+}
+
+type adminHandler struct {
+	session    *Session
+	opts       AdminOptions
+	redactKeys map[string]struct{}
+}
+
+// AdminHandler returns a handler which serves JSON endpoints to inspect and revoke the stored sessions:
+//
+//	GET    {prefix}/sessions?cursor=&limit=  lists the sessions, it requires a provider which implements Scanner
+//	GET    {prefix}/sessions/{id}            shows the decoded values of a session
+//	DELETE {prefix}/sessions/{id}            destroys a session
+//	GET    {prefix}/stats                    shows the count of sessions and the health of the provider
+//
+// Every request must be allowed by AdminOptions.Authorize
+func AdminHandler(s *Session, opts AdminOptions) fasthttp.RequestHandler {
+	h := &adminHandler{
+		session:    s,
+		opts:       opts,
+		redactKeys: make(map[string]struct{}, len(opts.RedactKeys)),
+	}
+
+	for _, key := range opts.RedactKeys {
+		h.redactKeys[key] = struct{}{}
+	}
+
+	return h.handle
+}
+
+func (h *adminHandler) handle(ctx *fasthttp.RequestCtx) {
+	if h.opts.Authorize == nil || !h.opts.Authorize(ctx) {
+		h.writeError(ctx, fasthttp.StatusForbidden, errors.New(fasthttp.StatusMessage(fasthttp.StatusForbidden)))
+		return
+	}
+
+	path := string(ctx.Path())
+	if !strings.HasPrefix(path, h.opts.Prefix) {
+		h.writeError(ctx, fasthttp.StatusNotFound, errors.New(fasthttp.StatusMessage(fasthttp.StatusNotFound)))
+		return
+	}
+
+	path = strings.Trim(path[len(h.opts.Prefix):], "/")
+
+	switch {
+	case path == "sessions" && ctx.IsGet():
+		h.list(ctx)
+	case path == "stats" && ctx.IsGet():
+		h.stats(ctx)
+	case strings.HasPrefix(path, "sessions/") && ctx.IsGet():
+		h.show(ctx, []byte(path[len("sessions/"):]))
+	case strings.HasPrefix(path, "sessions/") && ctx.IsDelete():
+		h.destroy(ctx, []byte(path[len("sessions/"):]))
+	case path == "sessions" || path == "stats" || strings.HasPrefix(path, "sessions/"):
+		h.writeError(ctx, fasthttp.StatusMethodNotAllowed, errors.New(fasthttp.StatusMessage(fasthttp.StatusMethodNotAllowed)))
+	default:
+		h.writeError(ctx, fasthttp.StatusNotFound, errors.New(fasthttp.StatusMessage(fasthttp.StatusNotFound)))
+	}
+}
+
+func (h *adminHandler) list(ctx *fasthttp.RequestCtx) {
+	limit := 0
+
+	if v := ctx.QueryArgs().Peek("limit"); len(v) > 0 {
+		n, err := strconv.Atoi(string(v))
+		if err != nil || n <= 0 {
+			h.writeError(ctx, fasthttp.StatusBadRequest, errors.New("Invalid limit"))
+			return
+		}
+
+		limit = n
+	}
+
+	cursor := string(ctx.QueryArgs().Peek("cursor"))
+
+	infos, next, err := h.session.ScanContext(requestContext(ctx), cursor, limit)
+	if errors.Is(err, ErrScanNotSupported) {
+		h.writeError(ctx, fasthttp.StatusNotImplemented, err)
+		return
+	} else if err != nil {
+		h.writeError(ctx, fasthttp.StatusInternalServerError, err)
+		return
+	}
+
+	result := adminSessionList{
+		Sessions: make([]adminSessionInfo, len(infos)),
+		Next:     next,
+	}
+
+	for i, info := range infos {
+		result.Sessions[i] = adminSessionInfo{
+			ID:         string(info.ID),
+			Size:       info.Size,
+			LastActive: adminTime(info.LastActive),
+			ExpiresAt:  adminTime(info.ExpiresAt),
+		}
+	}
+
+	h.writeJSON(ctx, fasthttp.StatusOK, result)
+}
+
+func (h *adminHandler) show(ctx *fasthttp.RequestCtx, id []byte) {
+	if !h.session.config.SessionIDValidatorFunc(id) {
+		h.writeError(ctx, fasthttp.StatusBadRequest, ErrInvalidSessionID)
+		return
+	}
+
+	p := h.session.provider.Load()
+	if p == nil {
+		h.writeError(ctx, fasthttp.StatusInternalServerError, ErrNotSetProvider)
+		return
+	}
+
+	store := NewStore()
+	store.sessionID = id
+
+	pctx, cancel := h.session.providerContext(requestContext(ctx), nil)
+	data, err := p.getData(pctx, store)
+	cancel()
+
+	if errors.Is(err, ErrSessionNotFound) {
+		h.writeError(ctx, fasthttp.StatusNotFound, err)
+		return
+	} else if err != nil {
+		h.writeError(ctx, fasthttp.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.session.decodeStore(store, data); err != nil {
+		h.writeError(ctx, fasthttp.StatusInternalServerError, err)
+		return
+	}
+
+	result := adminSession{
+		ID:         string(id),
+		CreatedAt:  adminTime(store.createdAt),
+		LastAccess: adminTime(store.lastAccess),
+		UserID:     store.userID,
+		Version:    store.version,
+		Values:     make(map[string]interface{}, len(store.data.KV)),
+	}
+
+	for key, value := range store.data.KV {
+		result.Values[key] = h.redact(key, value)
+	}
+
+	h.writeJSON(ctx, fasthttp.StatusOK, result)
+}
+
+func (h *adminHandler) destroy(ctx *fasthttp.RequestCtx, id []byte) {
+	if !h.session.config.SessionIDValidatorFunc(id) {
+		h.writeError(ctx, fasthttp.StatusBadRequest, ErrInvalidSessionID)
+		return
+	}
+
+	p := h.session.provider.Load()
+	if p == nil {
+		h.writeError(ctx, fasthttp.StatusInternalServerError, ErrNotSetProvider)
+		return
+	}
+
+	pctx, cancel := h.session.providerContext(requestContext(ctx), nil)
+	defer cancel()
+
+	if err := p.providerCtx.DestroyContext(pctx, id); err != nil {
+		h.writeError(ctx, fasthttp.StatusInternalServerError, err)
+		return
+	}
+
+	// The admin request is not the one of the session
+	runHook(h.session.config.Hooks.OnDestroy, nil, id, nil)
+
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// stats reports the count of sessions, and the health of the provider
+// checking that an unknown session id could be read, or by its HealthChecker
+func (h *adminHandler) stats(ctx *fasthttp.RequestCtx) {
+	p := h.session.provider.Load()
+	if p == nil {
+		h.writeError(ctx, fasthttp.StatusInternalServerError, ErrNotSetProvider)
+		return
+	}
+
+	pctx, cancel := h.session.providerContext(requestContext(ctx), nil)
+	defer cancel()
+
+	result := adminStats{
+		Count:   p.providerCtx.CountContext(pctx),
+		Healthy: true,
+	}
+
+	var err error
+
+	if p.checker != nil {
+		err = p.checker.HealthCheck(pctx)
+	} else if _, err = p.providerCtx.GetContext(pctx, h.session.config.SessionIDGeneratorFunc()); errors.Is(err, ErrSessionNotFound) {
+		err = nil
+	}
+
+	if err != nil {
+		result.Healthy = false
+		result.Error = err.Error()
+	}
+
+	statusCode := fasthttp.StatusOK
+	if !result.Healthy {
+		statusCode = fasthttp.StatusServiceUnavailable
+	}
+
+	h.writeJSON(ctx, statusCode, result)
+}
+
+func (h *adminHandler) redact(key string, value interface{}) interface{} {
+	if _, ok := h.redactKeys[key]; ok {
+		return adminRedactedValue
+	}
+
+	if h.opts.RedactFunc != nil {
+		return h.opts.RedactFunc(key, value)
+	}
+
+	return value
+}
+
+func (h *adminHandler) writeJSON(ctx *fasthttp.RequestCtx, statusCode int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		h.writeError(ctx, fasthttp.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.SetStatusCode(statusCode)
+	ctx.SetContentType("application/json")
+	ctx.SetBody(body)
+}
+
+func (h *adminHandler) writeError(ctx *fasthttp.RequestCtx, statusCode int, err error) {
+	body, _ := json.Marshal(adminError{Error: err.Error()})
+
+	ctx.SetStatusCode(statusCode)
+	ctx.SetContentType("application/json")
+	ctx.SetBody(body)
+}
+
+// adminTime returns nil for the zero time, so it's omitted from the response
+func adminTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
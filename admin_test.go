@@ -0,0 +1,347 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func newAdminTestRequest(method, uri string) *fasthttp.RequestCtx {
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(uri)
+
+	return ctx
+}
+
+func allowAdmin(ctx *fasthttp.RequestCtx) bool {
+	return true
+}
+
+func TestAdminHandler_Authorize(t *testing.T) {
+	s := New(Config{})
+
+	if err := s.SetProvider(new(mockScanner)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := newAdminTestRequest(fasthttp.MethodGet, "/admin/stats")
+
+	AdminHandler(s, AdminOptions{Prefix: "/admin"})(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusForbidden {
+		t.Errorf("Status code without Authorize == %d, want %d", v, fasthttp.StatusForbidden)
+	}
+
+	ctx = newAdminTestRequest(fasthttp.MethodGet, "/admin/stats")
+
+	AdminHandler(s, AdminOptions{
+		Prefix:    "/admin",
+		Authorize: func(ctx *fasthttp.RequestCtx) bool { return false },
+	})(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusForbidden {
+		t.Errorf("Status code of a rejected request == %d, want %d", v, fasthttp.StatusForbidden)
+	}
+}
+
+func TestAdminHandler_List(t *testing.T) {
+	s := New(Config{})
+	provider := &mockScanner{
+		sessions: []SessionInfo{{ID: []byte("id1"), Size: 1}, {ID: []byte("id2"), Size: 2}, {ID: []byte("id3"), Size: 3}},
+	}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := AdminHandler(s, AdminOptions{Prefix: "/admin", Authorize: allowAdmin})
+
+	ctx := newAdminTestRequest(fasthttp.MethodGet, "/admin/sessions?limit=2")
+	handler(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusOK {
+		t.Fatalf("Status code == %d, want %d", v, fasthttp.StatusOK)
+	}
+
+	var result adminSessionList
+	if err := json.Unmarshal(ctx.Response.Body(), &result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Sessions) != 2 || result.Sessions[1].ID != "id2" || result.Sessions[1].Size != 2 {
+		t.Errorf("Listed sessions == %v, want id1 and id2", result.Sessions)
+	}
+
+	ctx = newAdminTestRequest(fasthttp.MethodGet, "/admin/sessions?limit=2&cursor="+result.Next)
+	handler(ctx)
+
+	result = adminSessionList{}
+	if err := json.Unmarshal(ctx.Response.Body(), &result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result.Sessions) != 1 || result.Sessions[0].ID != "id3" || result.Next != "" {
+		t.Errorf("Listed sessions == %v (next %q), want id3", result.Sessions, result.Next)
+	}
+
+	ctx = newAdminTestRequest(fasthttp.MethodGet, "/admin/sessions?limit=invalid")
+	handler(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusBadRequest {
+		t.Errorf("Status code with an invalid limit == %d, want %d", v, fasthttp.StatusBadRequest)
+	}
+}
+
+func TestAdminHandler_ListNotSupported(t *testing.T) {
+	s := New(Config{})
+
+	if err := s.SetProvider(new(mockProvider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := newAdminTestRequest(fasthttp.MethodGet, "/sessions")
+	AdminHandler(s, AdminOptions{Authorize: allowAdmin})(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusNotImplemented {
+		t.Errorf("Status code == %d, want %d", v, fasthttp.StatusNotImplemented)
+	}
+}
+
+func TestAdminHandler_Show(t *testing.T) {
+	s := New(Config{})
+
+	store := NewStore()
+	store.Set("name", "foo")
+	store.Set("token", "secret")
+	store.Set("email", "foo@example.com")
+	store.userID = "user1"
+
+	data, err := s.encodeStore(store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	provider := &mockProvider{data: data}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := AdminHandler(s, AdminOptions{
+		Authorize:  allowAdmin,
+		RedactKeys: []string{"token"},
+		RedactFunc: func(key string, value interface{}) interface{} {
+			if key == "email" {
+				return "***"
+			}
+
+			return value
+		},
+	})
+
+	ctx := newAdminTestRequest(fasthttp.MethodGet, "/sessions/id1id1id1id1id1id1id1id1id1id1id")
+	handler(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusOK {
+		t.Fatalf("Status code == %d, want %d", v, fasthttp.StatusOK)
+	}
+
+	var result adminSession
+	if err := json.Unmarshal(ctx.Response.Body(), &result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.ID != "id1id1id1id1id1id1id1id1id1id1id" {
+		t.Errorf("Session id == %s, want %s", result.ID, "id1id1id1id1id1id1id1id1id1id1id")
+	}
+
+	if result.UserID != "user1" {
+		t.Errorf("Session user id == %s, want %s", result.UserID, "user1")
+	}
+
+	want := map[string]interface{}{
+		"name":  "foo",
+		"token": adminRedactedValue,
+		"email": "***",
+	}
+
+	for key, value := range want {
+		if v := result.Values[key]; v != value {
+			t.Errorf("Session value %s == %v, want %v", key, v, value)
+		}
+	}
+
+	provider.errGet = ErrSessionNotFound
+
+	ctx = newAdminTestRequest(fasthttp.MethodGet, "/sessions/id1id1id1id1id1id1id1id1id1id1id")
+	handler(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusNotFound {
+		t.Errorf("Status code of an unknown session == %d, want %d", v, fasthttp.StatusNotFound)
+	}
+}
+
+func TestAdminHandler_Destroy(t *testing.T) {
+	s := New(Config{})
+	provider := new(mockProvider)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := AdminHandler(s, AdminOptions{Authorize: allowAdmin})
+
+	ctx := newAdminTestRequest(fasthttp.MethodDelete, "/sessions/id1id1id1id1id1id1id1id1id1id1id")
+	handler(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusNoContent {
+		t.Errorf("Status code == %d, want %d", v, fasthttp.StatusNoContent)
+	}
+
+	if !provider.destroyed {
+		t.Error("The session is not destroyed")
+	}
+
+	ctx = newAdminTestRequest(fasthttp.MethodPost, "/sessions/id1id1id1id1id1id1id1id1id1id1id")
+	handler(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusMethodNotAllowed {
+		t.Errorf("Status code of an unknown method == %d, want %d", v, fasthttp.StatusMethodNotAllowed)
+	}
+}
+
+type mockAdminProvider struct {
+	mockProviderContext
+
+	destroyCtx context.Context
+}
+
+func (p *mockAdminProvider) DestroyContext(ctx context.Context, id []byte) error {
+	p.destroyCtx = ctx
+
+	return nil
+}
+
+func TestAdminHandler_RequestContext(t *testing.T) {
+	s := New(Config{})
+	provider := new(mockAdminProvider)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := AdminHandler(s, AdminOptions{Authorize: allowAdmin})
+
+	// Served request, so it could be used as a context
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Init(new(fasthttp.Request), nil, nil)
+	ctx.Request.Header.SetMethod(fasthttp.MethodDelete)
+	ctx.Request.SetRequestURI("/sessions/id1id1id1id1id1id1id1id1id1id1id")
+	ctx.SetUserValue("admin", "value")
+
+	handler(ctx)
+
+	if provider.destroyCtx == nil {
+		t.Fatal("The session is not destroyed")
+	}
+
+	if provider.destroyCtx.Value("admin") != "value" {
+		t.Error("The provider context does not derive from the admin request")
+	}
+
+	if v := RequestCtxFromContext(provider.destroyCtx); v != nil {
+		t.Errorf("RequestCtxFromContext() == %p, want nil since the admin request is not the one of the session", v)
+	}
+}
+
+func TestAdminHandler_Stats(t *testing.T) {
+	s := New(Config{})
+	provider := &mockProvider{countValue: 3, errGet: ErrSessionNotFound}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := AdminHandler(s, AdminOptions{Authorize: allowAdmin})
+
+	ctx := newAdminTestRequest(fasthttp.MethodGet, "/stats")
+	handler(ctx)
+
+	var result adminStats
+	if err := json.Unmarshal(ctx.Response.Body(), &result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Count != 3 || !result.Healthy {
+		t.Errorf("Stats == %+v, want count 3 and healthy", result)
+	}
+
+	provider.errGet = errors.New("connection refused")
+
+	ctx = newAdminTestRequest(fasthttp.MethodGet, "/stats")
+	handler(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusServiceUnavailable {
+		t.Errorf("Status code of an unhealthy provider == %d, want %d", v, fasthttp.StatusServiceUnavailable)
+	}
+}
+
+type mockHealthChecker struct {
+	mockProvider
+
+	errHealth error
+}
+
+func (p *mockHealthChecker) HealthCheck(ctx context.Context) error {
+	return p.errHealth
+}
+
+func TestAdminHandler_StatsHealthChecker(t *testing.T) {
+	s := New(Config{})
+
+	// The unknown session id is not read if the provider checks its own health
+	provider := &mockHealthChecker{mockProvider: mockProvider{errGet: errors.New("no request")}}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := AdminHandler(s, AdminOptions{Authorize: allowAdmin})
+
+	ctx := newAdminTestRequest(fasthttp.MethodGet, "/stats")
+	handler(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusOK {
+		t.Errorf("Status code of a healthy provider == %d, want %d", v, fasthttp.StatusOK)
+	}
+
+	provider.errHealth = errors.New("connection refused")
+
+	ctx = newAdminTestRequest(fasthttp.MethodGet, "/stats")
+	handler(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusServiceUnavailable {
+		t.Errorf("Status code of an unhealthy provider == %d, want %d", v, fasthttp.StatusServiceUnavailable)
+	}
+}
+
+func TestAdminHandler_MalformedSessionID(t *testing.T) {
+	s := New(Config{})
+
+	if err := s.SetProvider(new(mockProvider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := AdminHandler(s, AdminOptions{Authorize: allowAdmin})
+
+	ctx := newAdminTestRequest(fasthttp.MethodGet, "/sessions/id:*")
+	handler(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusBadRequest {
+		t.Errorf("Status code == %d, want %d", v, fasthttp.StatusBadRequest)
+	}
+}
@@ -1,7 +1,8 @@
 package session
 
 import (
-	"github.com/savsgio/gotils/bytes"
+	"strings"
+
 	"github.com/valyala/fasthttp"
 )
 
@@ -10,6 +11,7 @@ func NewDefaultConfig() Config {
 	config := Config{
 		CookieName:              defaultSessionKeyName,
 		Domain:                  defaultDomain,
+		CookiePath:              defaultCookiePath,
 		Expiration:              defaultExpiration,
 		GCLifetime:              defaultGCLifetime,
 		Secure:                  defaultSecure,
@@ -17,11 +19,11 @@ func NewDefaultConfig() Config {
 		SessionNameInURLQuery:   defaultSessionKeyName,
 		SessionIDInHTTPHeader:   defaultSessionIDInHTTPHeader,
 		SessionNameInHTTPHeader: defaultSessionKeyName,
-		cookieLen:               defaultCookieLen,
+		SessionIDLength:         defaultSessionIDLength,
 	}
 
-	// default sessionIdGeneratorFunc
-	config.SessionIDGeneratorFunc = config.defaultSessionIDGenerator
+	// The default SessionIDGeneratorFunc and SessionIDValidatorFunc are set by New,
+	// so they use the final SessionIDLength and SessionIDEncoding
 
 	// default isSecureFunc
 	config.IsSecureFunc = config.defaultIsSecureFunc
@@ -30,9 +32,73 @@ func NewDefaultConfig() Config {
 }
 
 func (c *Config) defaultSessionIDGenerator() []byte {
-	return bytes.Rand(make([]byte, c.cookieLen))
+	return randomSessionID(c.SessionIDEncoding.alphabet(), c.SessionIDLength)
+}
+
+func (c *Config) defaultSessionIDValidator(id []byte) bool {
+	return len(id) == c.SessionIDLength && c.SessionIDEncoding.contains(id)
+}
+
+func anySessionIDValidator(id []byte) bool {
+	return true
 }
 
 func (c *Config) defaultIsSecureFunc(ctx *fasthttp.RequestCtx) bool {
 	return ctx.IsTLS()
 }
+
+func (c *Config) defaultMiddlewareLoadErrorHandler(ctx *fasthttp.RequestCtx, err error) {
+	c.Logger.Printf("session load error: %v", err)
+
+	ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+}
+
+func (c *Config) defaultMiddlewareSaveErrorHandler(ctx *fasthttp.RequestCtx, err error) {
+	c.Logger.Printf("session save error: %v", err)
+
+	ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+}
+
+// cookieAttrs returns the attributes of the session cookie for the given request
+func (c *Config) cookieAttrs(ctx *fasthttp.RequestCtx) cookieAttrs {
+	secure := c.Secure && c.IsSecureFunc(ctx)
+
+	// The clients reject these cookies if they are not secure
+	if c.CookiePartitioned || strings.HasPrefix(c.CookieName, secureCookiePrefix) || strings.HasPrefix(c.CookieName, hostCookiePrefix) {
+		secure = true
+	}
+
+	return cookieAttrs{
+		domain:      c.Domain,
+		path:        c.CookiePath,
+		sameSite:    c.CookieSameSite,
+		secure:      secure,
+		httpOnly:    !c.CookieDisableHTTPOnly,
+		partitioned: c.CookiePartitioned,
+		maxAge:      c.CookieMaxAge,
+	}
+}
+
+// validateCookie checks the requirements of the cookie name prefixes
+func (c *Config) validateCookie() error {
+	return ValidateCookie(c.CookieName, c.CookiePath, c.Domain, c.Secure)
+}
+
+// ValidateCookie checks that the attributes of a cookie meet the requirements of its name prefix:
+// the __Secure- cookies must be secure, and the __Host- cookies must also have the "/" path and no domain
+//
+// Returns ErrInvalidSecureCookie or ErrInvalidHostCookie if they are not met
+func ValidateCookie(name, path, domain string, secure bool) error {
+	switch {
+	case strings.HasPrefix(name, hostCookiePrefix):
+		if !secure || path != "/" || domain != "" {
+			return ErrInvalidHostCookie
+		}
+	case strings.HasPrefix(name, secureCookiePrefix):
+		if !secure {
+			return ErrInvalidSecureCookie
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/valyala/fasthttp"
@@ -46,12 +47,12 @@ func Test_NewDefaultConfig(t *testing.T) {
 		t.Errorf("NewDefaultConfig().SessionNameInHTTPHeader == %s, want %s", cfg.SessionNameInHTTPHeader, defaultSessionKeyName)
 	}
 
-	if cfg.cookieLen != defaultCookieLen {
-		t.Errorf("NewDefaultConfig().cookieLen == %d, want %d", cfg.cookieLen, defaultCookieLen)
+	if cfg.SessionIDLength != defaultSessionIDLength {
+		t.Errorf("NewDefaultConfig().SessionIDLength == %d, want %d", cfg.SessionIDLength, defaultSessionIDLength)
 	}
 
-	if reflect.ValueOf(cfg.SessionIDGeneratorFunc).Pointer() != reflect.ValueOf(cfg.defaultSessionIDGenerator).Pointer() {
-		t.Errorf("NewDefaultConfig().SessionIDGeneratorFunc == %p, want %p", cfg.SessionIDGeneratorFunc, cfg.defaultSessionIDGenerator)
+	if cfg.SessionIDGeneratorFunc != nil || cfg.SessionIDValidatorFunc != nil {
+		t.Error("NewDefaultConfig() session id funcs must be set by New")
 	}
 
 	if reflect.ValueOf(cfg.IsSecureFunc).Pointer() != reflect.ValueOf(cfg.defaultIsSecureFunc).Pointer() {
@@ -60,12 +61,69 @@ func Test_NewDefaultConfig(t *testing.T) {
 }
 
 func TestConfig_defaultSessionIDGenerator(t *testing.T) {
-	cfg := Config{cookieLen: 10}
+	encodings := []SessionIDEncoding{SessionIDBase62, SessionIDBase64URL, SessionIDHex}
 
-	id := cfg.defaultSessionIDGenerator()
+	for _, encoding := range encodings {
+		cfg := Config{SessionIDLength: 40, SessionIDEncoding: encoding}
 
-	if len(id) != int(cfg.cookieLen) {
-		t.Errorf("Config.defaultSessionIDGenerator() len == %d, want %d", len(id), cfg.cookieLen)
+		id := cfg.defaultSessionIDGenerator()
+
+		if len(id) != cfg.SessionIDLength {
+			t.Errorf("Config.defaultSessionIDGenerator() len == %d, want %d", len(id), cfg.SessionIDLength)
+		}
+
+		if !cfg.defaultSessionIDValidator(id) {
+			t.Errorf("Config.defaultSessionIDValidator(%s) == %v, want %v", id, false, true)
+		}
+	}
+}
+
+func TestNewDefaultConfig_sessionIDSettings(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.SessionIDLength = 64
+	cfg.SessionIDEncoding = SessionIDHex
+
+	s := New(cfg)
+
+	id := s.config.SessionIDGeneratorFunc()
+
+	if len(id) != cfg.SessionIDLength || !SessionIDHex.contains(id) {
+		t.Errorf("Session.SessionIDGeneratorFunc() == %s, want %d hex characters", id, cfg.SessionIDLength)
+	}
+
+	valid := []byte(strings.Repeat("0123456789abcdef", 4))
+
+	if !s.config.SessionIDValidatorFunc(valid) {
+		t.Errorf("Session.SessionIDValidatorFunc(%s) == %v, want %v", valid, false, true)
+	}
+
+	if s.config.SessionIDValidatorFunc([]byte("asd2324nasd2324nasd2324nasd2324n")) {
+		t.Errorf("Session.SessionIDValidatorFunc() of a base62 id == %v, want %v", true, false)
+	}
+}
+
+func TestConfig_defaultSessionIDValidator(t *testing.T) {
+	testCases := []struct {
+		encoding SessionIDEncoding
+		id       string
+		want     bool
+	}{
+		{encoding: SessionIDBase62, id: "abcdefghijABCDEFGHIJ0123", want: true},
+		{encoding: SessionIDBase62, id: "abcdefghijABCDEFGHIJ012", want: false},
+		{encoding: SessionIDBase62, id: "abcdefghijABCDEFGHIJ01-_", want: false},
+		{encoding: SessionIDBase62, id: "abcdefghij:*CDEFGHIJ0123", want: false},
+		{encoding: SessionIDBase64URL, id: "abcdefghijABCDEFGHIJ01-_", want: true},
+		{encoding: SessionIDBase64URL, id: "abcdefghijABCDEFGHIJ01+/", want: false},
+		{encoding: SessionIDHex, id: "0123456789abcdef01234567", want: true},
+		{encoding: SessionIDHex, id: "0123456789ABCDEF01234567", want: false},
+	}
+
+	for _, tc := range testCases {
+		cfg := Config{SessionIDLength: 24, SessionIDEncoding: tc.encoding}
+
+		if v := cfg.defaultSessionIDValidator([]byte(tc.id)); v != tc.want {
+			t.Errorf("Config.defaultSessionIDValidator(%s) == %v, want %v", tc.id, v, tc.want)
+		}
 	}
 }
 
@@ -4,14 +4,57 @@ import "time"
 
 const defaultSessionKeyName = "sessionid"
 const defaultDomain = ""
+const defaultCookiePath = "/"
 const defaultExpiration = 2 * time.Hour
 const defaultGCLifetime = 1 * time.Minute
 const defaultSecure = true
 const defaultSessionIDInURLQuery = false
 const defaultSessionIDInHTTPHeader = false
-const defaultCookieLen uint32 = 32
+const defaultSessionIDLength = 32
+const defaultScanLimit = 100
 
 // If set the cookie expiration when the browser is closed (-1), set the expiration as a keep alive (2 days)
 // so as not to keep dead sessions for a long time
 const keepAliveExpiration = 2 * 24 * time.Hour
-const expirationAttrKey = "__store:expiration__"
+
+// Cookie name prefixes which restrict the cookie attributes
+const secureCookiePrefix = "__Secure-"
+const hostCookiePrefix = "__Host-"
+
+// Keys of the envelope which contains the session metadata and values
+const envelopeMetaKey = "__session:meta__"
+const envelopeDataKey = "__session:data__"
+const envelopeFlashKey = "__session:flash__"
+
+const metaCreatedAtKey = "created_at"
+const metaLastAccessKey = "last_access"
+const metaExpirationKey = "expiration"
+const metaFingerprintKey = "fingerprint"
+const metaUserIDKey = "user_id"
+
+// Key of the session expiration stored with the values, before the envelope
+const legacyExpirationKey = "__store:expiration__"
+
+// Codec of the session data if neither SessionCodec nor EncodeFunc are set
+const defaultSessionCodec = SessionCodecBase64
+
+// Header of the session data encoded with a registered codec: magic, version and codec id.
+// The magic is not a base64 or MessagePack map prefix, so the legacy data without header is recognized.
+const formatMagic = '$'
+const formatVersion = '1'
+const formatHeaderLen = 3
+
+// Key of the objects which wrap the byte slices encoded by JSONEncode
+const jsonBytesKey = "__session:bytes__"
+
+// Minimum bits of entropy of the session ids
+const minSessionIDEntropy = 128
+
+// Maximum number of times that the MergeFunc is called on a single Save
+const maxMergeAttempts = 3
+
+// Interval between the attempts to acquire a lock held by another owner
+const lockRetryInterval = 10 * time.Millisecond
+
+// Value shown by the admin handler instead of the redacted session values
+const adminRedactedValue = "[REDACTED]"
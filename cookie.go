@@ -14,47 +14,59 @@ func (c *cookie) get(ctx *fasthttp.RequestCtx, name string) []byte {
 	return ctx.Request.Header.Cookie(name)
 }
 
-func (c *cookie) set(ctx *fasthttp.RequestCtx, name string, value []byte, domain string, expiration time.Duration, secure bool, sameSite fasthttp.CookieSameSite) {
+func (c *cookie) set(ctx *fasthttp.RequestCtx, name string, value []byte, expiration time.Duration, attrs cookieAttrs) {
 	cookie := fasthttp.AcquireCookie()
 
 	cookie.SetKey(name)
-	cookie.SetPath("/")
-	cookie.SetHTTPOnly(true)
-	cookie.SetDomain(domain)
 	cookie.SetValueBytes(value)
-	cookie.SetSameSite(sameSite)
+	attrs.apply(cookie)
 
-	if expiration >= 0 {
-		if expiration == 0 {
-			cookie.SetExpire(fasthttp.CookieExpireUnlimited)
+	// The cookie without expiration is removed when the browser is closed
+	if expiration > 0 {
+		if attrs.maxAge {
+			cookie.SetMaxAge(int(expiration.Seconds()))
 		} else {
 			cookie.SetExpire(time.Now().Add(expiration))
 		}
 	}
 
-	if secure {
-		cookie.SetSecure(true)
-	}
-
 	ctx.Request.Header.SetCookieBytesKV(cookie.Key(), cookie.Value())
 	ctx.Response.Header.SetCookie(cookie)
 
 	fasthttp.ReleaseCookie(cookie)
 }
 
-func (c *cookie) delete(ctx *fasthttp.RequestCtx, name string) {
+// delete expires the cookie in the client,
+// with the same attributes that it was set, otherwise the client keeps it
+func (c *cookie) delete(ctx *fasthttp.RequestCtx, name string, attrs cookieAttrs) {
 	ctx.Request.Header.DelCookie(name)
 	ctx.Response.Header.DelCookie(name)
 
 	cookie := fasthttp.AcquireCookie()
 	cookie.SetKey(name)
 	cookie.SetValue("")
-	cookie.SetPath("/")
-	cookie.SetHTTPOnly(true)
-	//RFC says 1 second, but let's do it 1 minute to make sure is working...
-	exp := time.Now().Add(-1 * time.Minute)
-	cookie.SetExpire(exp)
+	attrs.apply(cookie)
+
+	if attrs.maxAge {
+		cookie.SetMaxAge(-1)
+	} else {
+		//RFC says 1 second, but let's do it 1 minute to make sure is working...
+		exp := time.Now().Add(-1 * time.Minute)
+		cookie.SetExpire(exp)
+	}
+
 	ctx.Response.Header.SetCookie(cookie)
 
 	fasthttp.ReleaseCookie(cookie)
 }
+
+// apply sets the attributes to the given cookie
+func (attrs cookieAttrs) apply(cookie *fasthttp.Cookie) {
+	// SetPartitioned overrides the path, so it must be set first
+	cookie.SetPartitioned(attrs.partitioned)
+	cookie.SetPath(attrs.path)
+	cookie.SetHTTPOnly(attrs.httpOnly)
+	cookie.SetDomain(attrs.domain)
+	cookie.SetSameSite(attrs.sameSite)
+	cookie.SetSecure(attrs.secure)
+}
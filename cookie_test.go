@@ -1,6 +1,7 @@
 package session
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -41,8 +42,10 @@ func TestCookie_set(t *testing.T) {
 	secure := true
 	samesite := fasthttp.CookieSameSiteLaxMode
 
+	attrs := cookieAttrs{domain: domain, path: path, sameSite: samesite, secure: secure, httpOnly: true}
+
 	now := time.Now()
-	cookie.set(ctx, key, value, domain, expiration, secure, samesite)
+	cookie.set(ctx, key, value, expiration, attrs)
 
 	resultCookie := new(fasthttp.Cookie)
 	resultCookie.SetKey(key)
@@ -87,11 +90,14 @@ func TestCookie_delete(t *testing.T) {
 
 	key := "key"
 	value := []byte("")
-	path := "/"
+	path := "/path"
+	domain := "domain"
 	expiration := -1 * time.Minute
 
+	attrs := cookieAttrs{domain: domain, path: path, secure: true, httpOnly: true}
+
 	now := time.Now()
-	cookie.delete(ctx, key)
+	cookie.delete(ctx, key, attrs)
 
 	resultCookie := new(fasthttp.Cookie)
 	resultCookie.SetKey(key)
@@ -109,6 +115,14 @@ func TestCookie_delete(t *testing.T) {
 		t.Errorf("cookie.set() Value == %s, want %s", resultCookie.Value(), value)
 	}
 
+	if string(resultCookie.Domain()) != domain {
+		t.Errorf("cookie.delete() Domain == %s, want %s", resultCookie.Domain(), domain)
+	}
+
+	if !resultCookie.Secure() {
+		t.Errorf("cookie.delete() Secure == %v, want %v", false, true)
+	}
+
 	if resultCookie.Expire().Unix() != now.Add(expiration).Unix() {
 		t.Errorf("cookie.set() Expire == %v, want %v", resultCookie.Expire(), expiration)
 	}
@@ -117,3 +131,169 @@ func TestCookie_delete(t *testing.T) {
 		t.Errorf("cookie.set() request value == %s, want %s", v, value)
 	}
 }
+
+func TestCookie_setMaxAge(t *testing.T) {
+	ctx := new(fasthttp.RequestCtx)
+	cookie := newCookie()
+
+	key := "key"
+	expiration := 10 * time.Second
+
+	cookie.set(ctx, key, []byte("value"), expiration, cookieAttrs{path: "/", maxAge: true})
+
+	resultCookie := new(fasthttp.Cookie)
+	resultCookie.SetKey(key)
+	ctx.Response.Header.Cookie(resultCookie)
+
+	if resultCookie.MaxAge() != int(expiration.Seconds()) {
+		t.Errorf("cookie.set() MaxAge == %d, want %d", resultCookie.MaxAge(), int(expiration.Seconds()))
+	}
+
+	if !resultCookie.Expire().Equal(fasthttp.CookieExpireUnlimited) {
+		t.Errorf("cookie.set() Expire == %v, want %v", resultCookie.Expire(), fasthttp.CookieExpireUnlimited)
+	}
+
+	ctx.Response.Reset()
+	cookie.delete(ctx, key, cookieAttrs{path: "/", maxAge: true})
+
+	if v := string(ctx.Response.Header.PeekCookie(key)); !strings.Contains(v, "max-age=0") {
+		t.Errorf("cookie.delete() == %s, want max-age=0", v)
+	}
+}
+
+func TestCookie_setWithoutExpiration(t *testing.T) {
+	for _, maxAge := range []bool{false, true} {
+		ctx := new(fasthttp.RequestCtx)
+		cookie := newCookie()
+
+		key := "key"
+
+		cookie.set(ctx, key, []byte("value"), 0, cookieAttrs{path: "/", maxAge: maxAge})
+
+		v := strings.ToLower(string(ctx.Response.Header.PeekCookie(key)))
+		if v == "" {
+			t.Fatalf("maxAge %v: the cookie is not set", maxAge)
+		}
+
+		if strings.Contains(v, "expires=") || strings.Contains(v, "max-age=") {
+			t.Errorf("maxAge %v: cookie.set() == %s, want a cookie without expiration", maxAge, v)
+		}
+	}
+}
+
+func TestCookie_setPartitioned(t *testing.T) {
+	ctx := new(fasthttp.RequestCtx)
+	cookie := newCookie()
+
+	key := "key"
+	path := "/path"
+
+	cookie.set(ctx, key, []byte("value"), time.Minute, cookieAttrs{path: path, secure: true, partitioned: true})
+
+	resultCookie := new(fasthttp.Cookie)
+	resultCookie.SetKey(key)
+	ctx.Response.Header.Cookie(resultCookie)
+
+	if !resultCookie.Partitioned() {
+		t.Errorf("cookie.set() Partitioned == %v, want %v", false, true)
+	}
+
+	if string(resultCookie.Path()) != path {
+		t.Errorf("cookie.set() Path == %s, want %s", resultCookie.Path(), path)
+	}
+
+	if resultCookie.HTTPOnly() {
+		t.Errorf("cookie.set() HTTPOnly == %v, want %v", true, false)
+	}
+}
+
+func TestConfig_cookieAttrs(t *testing.T) {
+	ctx := new(fasthttp.RequestCtx)
+
+	cfg := NewDefaultConfig()
+	cfg.CookieDisableHTTPOnly = true
+
+	attrs := cfg.cookieAttrs(ctx)
+
+	if attrs.path != defaultCookiePath {
+		t.Errorf("Config.cookieAttrs() path == %s, want %s", attrs.path, defaultCookiePath)
+	}
+
+	if attrs.httpOnly {
+		t.Errorf("Config.cookieAttrs() httpOnly == %v, want %v", true, false)
+	}
+
+	if attrs.secure {
+		t.Errorf("Config.cookieAttrs() secure == %v, want %v", true, false)
+	}
+
+	cfg.CookieName = "__Host-session"
+
+	if attrs := cfg.cookieAttrs(ctx); !attrs.secure {
+		t.Errorf("Config.cookieAttrs() with __Host- prefix secure == %v, want %v", false, true)
+	}
+}
+
+func TestConfig_validateCookie(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config func(cfg *Config)
+		err    error
+	}{
+		{
+			name:   "no prefix",
+			config: func(cfg *Config) { cfg.Secure = false },
+		},
+		{
+			name:   "secure prefix",
+			config: func(cfg *Config) { cfg.CookieName = "__Secure-session" },
+		},
+		{
+			name: "secure prefix without secure",
+			config: func(cfg *Config) {
+				cfg.CookieName = "__Secure-session"
+				cfg.Secure = false
+			},
+			err: ErrInvalidSecureCookie,
+		},
+		{
+			name:   "host prefix",
+			config: func(cfg *Config) { cfg.CookieName = "__Host-session" },
+		},
+		{
+			name: "host prefix with domain",
+			config: func(cfg *Config) {
+				cfg.CookieName = "__Host-session"
+				cfg.Domain = "example.com"
+			},
+			err: ErrInvalidHostCookie,
+		},
+		{
+			name: "host prefix with path",
+			config: func(cfg *Config) {
+				cfg.CookieName = "__Host-session"
+				cfg.CookiePath = "/path"
+			},
+			err: ErrInvalidHostCookie,
+		},
+		{
+			name: "host prefix without secure",
+			config: func(cfg *Config) {
+				cfg.CookieName = "__Host-session"
+				cfg.Secure = false
+			},
+			err: ErrInvalidHostCookie,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			tc.config(&cfg)
+
+			if err := New(cfg).SetProvider(new(mockProvider)); err != tc.err {
+				t.Errorf("Session.SetProvider() error == %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
@@ -1,11 +1,35 @@
 package session
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
 )
 
 var b64Encoding = base64.StdEncoding
 
+var cborEncMode, _ = cbor.EncOptions{
+	Time:    cbor.TimeRFC3339Nano,
+	TimeTag: cbor.EncTagRequired,
+}.EncMode()
+
+var cborDecMode, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+	IntDec:         cbor.IntDecConvertSignedOrBigInt,
+}.DecMode()
+
+func init() {
+	// The envelope and the flash messages are nested in interface values
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register(time.Time{})
+}
+
 // MSGPEncode MessagePack encode
 func MSGPEncode(src Dict) ([]byte, error) {
 	if len(src.KV) == 0 {
@@ -22,9 +46,7 @@ func MSGPEncode(src Dict) ([]byte, error) {
 
 // MSGPDecode MessagePack decode
 func MSGPDecode(dst *Dict, src []byte) error {
-	for k := range dst.KV {
-		delete(dst.KV, k)
-	}
+	resetDict(dst)
 
 	if len(src) == 0 {
 		return nil
@@ -58,3 +80,176 @@ func Base64Decode(dst *Dict, src []byte) error {
 
 	return MSGPDecode(dst, tmp[:n])
 }
+
+// JSONEncode JSON encode
+//
+// The integers are decoded by JSONDecode as int64 and the other numbers as float64.
+// The byte slices are encoded as objects with a reserved key, so they are decoded as byte slices.
+func JSONEncode(src Dict) ([]byte, error) {
+	if len(src.KV) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(jsonEncodeValue(src.KV))
+}
+
+// jsonEncodeValue returns a copy of the given value with its byte slices wrapped in objects
+func jsonEncodeValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case []byte:
+		return map[string]interface{}{jsonBytesKey: b64Encoding.EncodeToString(val)}
+	case map[string]interface{}:
+		dst := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			dst[k] = jsonEncodeValue(item)
+		}
+
+		return dst
+	case []interface{}:
+		dst := make([]interface{}, len(val))
+		for i, item := range val {
+			dst[i] = jsonEncodeValue(item)
+		}
+
+		return dst
+	}
+
+	return v
+}
+
+// JSONDecode JSON decode
+func JSONDecode(dst *Dict, src []byte) error {
+	resetDict(dst)
+
+	if len(src) == 0 {
+		return nil
+	}
+
+	kv := make(map[string]interface{})
+
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+
+	if err := dec.Decode(&kv); err != nil {
+		return err
+	}
+
+	for k, v := range kv {
+		dst.KV[k] = jsonDecodeValue(v)
+	}
+
+	return nil
+}
+
+// jsonDecodeValue converts the JSON numbers of the given decoded value to int64 or float64,
+// and the wrapped byte slices to byte slices
+func jsonDecodeValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n
+		}
+
+		n, _ := val.Float64()
+
+		return n
+	case map[string]interface{}:
+		if encoded, ok := val[jsonBytesKey].(string); ok && len(val) == 1 {
+			if b, err := b64Encoding.DecodeString(encoded); err == nil {
+				return b
+			}
+		}
+
+		for k, item := range val {
+			val[k] = jsonDecodeValue(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = jsonDecodeValue(item)
+		}
+	}
+
+	return v
+}
+
+// GobEncode gob encode
+//
+// The types of the values, other than the basic types, maps and slices of interfaces and time.Time,
+// must be registered with gob.Register
+func GobEncode(src Dict) ([]byte, error) {
+	if len(src.KV) == 0 {
+		return nil, nil
+	}
+
+	buf := new(bytes.Buffer)
+
+	if err := gob.NewEncoder(buf).Encode(src.KV); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// GobDecode gob decode
+func GobDecode(dst *Dict, src []byte) error {
+	resetDict(dst)
+
+	if len(src) == 0 {
+		return nil
+	}
+
+	kv := make(map[string]interface{})
+
+	if err := gob.NewDecoder(bytes.NewReader(src)).Decode(&kv); err != nil {
+		return err
+	}
+
+	for k, v := range kv {
+		dst.KV[k] = v
+	}
+
+	return nil
+}
+
+// CBOREncode CBOR encode
+//
+// The integers are decoded by CBORDecode as int64, or as big.Int if they overflow it
+func CBOREncode(src Dict) ([]byte, error) {
+	if len(src.KV) == 0 {
+		return nil, nil
+	}
+
+	return cborEncMode.Marshal(src.KV)
+}
+
+// CBORDecode CBOR decode
+func CBORDecode(dst *Dict, src []byte) error {
+	resetDict(dst)
+
+	if len(src) == 0 {
+		return nil
+	}
+
+	kv := make(map[string]interface{})
+
+	if err := cborDecMode.Unmarshal(src, &kv); err != nil {
+		return err
+	}
+
+	for k, v := range kv {
+		dst.KV[k] = v
+	}
+
+	return nil
+}
+
+// resetDict removes all the values of the given dict
+func resetDict(dst *Dict) {
+	if dst.KV == nil {
+		dst.KV = make(map[string]interface{})
+	}
+
+	for k := range dst.KV {
+		delete(dst.KV, k)
+	}
+}
@@ -3,6 +3,7 @@ package session
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func getSRC() Dict {
@@ -54,6 +55,67 @@ func TestBase64EncodeDecode(t *testing.T) {
 	}
 }
 
+func TestEncodeDecode(t *testing.T) {
+	createdAt := time.Unix(0, time.Now().UnixNano()).UTC()
+
+	src := Dict{
+		KV: map[string]interface{}{
+			"string": "foo",
+			"int":    int64(-1),
+			"big":    int64(time.Now().UnixNano()),
+			"float":  1.5,
+			"bool":   true,
+			"bytes":  []byte("bytes"),
+			"slice":  []interface{}{"a", int64(-2), []byte("b")},
+			"map":    map[string]interface{}{"k": "v"},
+			"time":   createdAt,
+		},
+	}
+
+	encodings := []struct {
+		name   string
+		encode func(src Dict) ([]byte, error)
+		decode func(dst *Dict, src []byte) error
+	}{
+		{name: "json", encode: JSONEncode, decode: JSONDecode},
+		{name: "gob", encode: GobEncode, decode: GobDecode},
+		{name: "cbor", encode: CBOREncode, decode: CBORDecode},
+	}
+
+	for _, enc := range encodings {
+		data, err := enc.encode(src)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", enc.name, err)
+		}
+
+		dst := getDST()
+		dst.KV["old"] = "value"
+
+		if err := enc.decode(&dst, data); err != nil {
+			t.Fatalf("%s: unexpected error: %v", enc.name, err)
+		}
+
+		if _, ok := dst.KV["old"]; ok {
+			t.Errorf("%s: the previous values of 'dst' are not removed", enc.name)
+		}
+
+		for _, key := range []string{"string", "int", "big", "float", "bool", "bytes", "slice", "map"} {
+			if !reflect.DeepEqual(src.KV[key], dst.KV[key]) {
+				t.Errorf("%s: dst[%s] == %#v, want %#v", enc.name, key, dst.KV[key], src.KV[key])
+			}
+		}
+
+		// The time is decoded as a RFC 3339 string by JSON
+		if enc.name != "json" && !reflect.DeepEqual(src.KV["time"], dst.KV["time"]) {
+			t.Errorf("%s: dst[time] == %#v, want %#v", enc.name, dst.KV["time"], src.KV["time"])
+		}
+
+		if err := enc.decode(&dst, nil); err != nil || len(dst.KV) != 0 {
+			t.Errorf("%s: decode of empty data == (%v, %v), want empty", enc.name, dst.KV, err)
+		}
+	}
+}
+
 func BenchmarkMSGPEncode(b *testing.B) {
 	src := getSRC()
 
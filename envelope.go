@@ -0,0 +1,109 @@
+package session
+
+import (
+	"time"
+)
+
+// envelope returns the values of the store wrapped with its metadata,
+// recording the given time as the last access
+func (s *Store) envelope(now time.Time) Dict {
+	meta := map[string]interface{}{
+		metaCreatedAtKey:  s.createdAt.UnixNano(),
+		metaLastAccessKey: now.UnixNano(),
+	}
+
+	if s.hasExpiration {
+		meta[metaExpirationKey] = int64(s.expiration)
+	}
+
+	if s.fingerprint != "" {
+		meta[metaFingerprintKey] = s.fingerprint
+	}
+
+	if s.userID != "" {
+		meta[metaUserIDKey] = s.userID
+	}
+
+	dst := Dict{
+		KV: map[string]interface{}{
+			envelopeMetaKey: meta,
+			envelopeDataKey: s.data.KV,
+		},
+	}
+
+	if len(s.flashes) > 0 {
+		dst.KV[envelopeFlashKey] = s.flashesEnvelope()
+	}
+
+	return dst
+}
+
+// openEnvelope moves the metadata of the decoded envelope to the store fields,
+// keeping only the values in the store
+//
+// The expiration of the sessions stored before the envelope is decoded from their values
+func (s *Store) openEnvelope() {
+	meta, ok := s.data.KV[envelopeMetaKey].(map[string]interface{})
+	if !ok {
+		s.openLegacy()
+		return
+	}
+
+	data, _ := s.data.KV[envelopeDataKey].(map[string]interface{})
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
+	flashes, _ := s.data.KV[envelopeFlashKey].(map[string]interface{})
+
+	s.data.KV = data
+	s.openFlashes(flashes)
+
+	if createdAt, ok := metaInt64(meta[metaCreatedAtKey]); ok {
+		s.createdAt = time.Unix(0, createdAt)
+	}
+
+	if lastAccess, ok := metaInt64(meta[metaLastAccessKey]); ok {
+		s.lastAccess = time.Unix(0, lastAccess)
+	}
+
+	if expiration, ok := metaInt64(meta[metaExpirationKey]); ok {
+		s.expiration = time.Duration(expiration)
+		s.hasExpiration = true
+	}
+
+	if fingerprint, ok := meta[metaFingerprintKey].(string); ok {
+		s.fingerprint = fingerprint
+	}
+
+	if userID, ok := meta[metaUserIDKey].(string); ok {
+		s.userID = userID
+	}
+}
+
+// openLegacy moves the expiration stored with the values to the store fields
+func (s *Store) openLegacy() {
+	if expiration, ok := metaInt64(s.data.KV[legacyExpirationKey]); ok {
+		s.expiration = time.Duration(expiration)
+		s.hasExpiration = true
+	}
+
+	delete(s.data.KV, legacyExpirationKey)
+}
+
+// metaInt64 returns the integer of a decoded metadata value,
+// since each encoding could decode it as a different numeric type
+func metaInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSession_encodeDecodeStore(t *testing.T) {
+	s := New(Config{})
+
+	createdAt := time.Unix(0, time.Now().Add(-time.Hour).UnixNano())
+	expiration := 10 * time.Minute
+
+	store := NewStore()
+	store.Set("k", "v")
+	store.createdAt = createdAt
+	store.fingerprint = "fingerprint"
+	store.userID = "user1"
+
+	if err := store.SetExpiration(expiration); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := len(store.GetAll().KV); v != 1 {
+		t.Errorf("Store values == %d, want %d", v, 1)
+	}
+
+	store.Flush()
+	store.Set("k", "v")
+
+	if v := store.GetExpiration(); v != expiration {
+		t.Errorf("Store.GetExpiration() after flush == %v, want %v", v, expiration)
+	}
+
+	before := time.Now()
+
+	data, err := s.encodeStore(store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := NewStore()
+
+	if err := s.decodeStore(result, data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := result.Get("k"); v != "v" {
+		t.Errorf("Store.Get() == %v, want %v", v, "v")
+	}
+
+	if v := len(result.GetAll().KV); v != 1 {
+		t.Errorf("Store values == %d, want %d", v, 1)
+	}
+
+	if v := result.CreatedAt(); !v.Equal(createdAt) {
+		t.Errorf("Store.CreatedAt() == %v, want %v", v, createdAt)
+	}
+
+	if v := result.LastAccess(); v.Before(before) || v.After(time.Now()) {
+		t.Errorf("Store.LastAccess() == %v, want the time of the encoding", v)
+	}
+
+	if result.fingerprint != store.fingerprint {
+		t.Errorf("Store.fingerprint == %s, want %s", result.fingerprint, store.fingerprint)
+	}
+
+	metadata := result.Metadata()
+
+	if metadata.Expiration != expiration || !result.HasExpirationChanged() {
+		t.Errorf("Metadata.Expiration == %v, want %v", metadata.Expiration, expiration)
+	}
+
+	if metadata.UserID != "user1" {
+		t.Errorf("Metadata.UserID == %s, want %s", metadata.UserID, "user1")
+	}
+}
+
+func TestSession_decodeStoreLegacy(t *testing.T) {
+	s := New(Config{})
+
+	expiration := 10 * time.Minute
+
+	legacy := Dict{
+		KV: map[string]interface{}{
+			"k":                 "v",
+			legacyExpirationKey: int64(expiration),
+		},
+	}
+
+	data, err := s.config.EncodeFunc(legacy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	store := NewStore()
+
+	if err := s.decodeStore(store, data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := store.GetAll().KV; len(v) != 1 || v["k"] != "v" {
+		t.Errorf("Store values == %v, want only %s", v, "k")
+	}
+
+	if v := store.Metadata().Expiration; v != expiration {
+		t.Errorf("Metadata.Expiration == %v, want %v", v, expiration)
+	}
+}
@@ -7,4 +7,54 @@ import (
 var (
 	ErrNotSetProvider = errors.New("Not setted a session provider")
 	ErrEmptySessionID = errors.New("Empty session id")
+
+	// ErrInvalidSessionID is returned when a session id is not valid
+	// according to the SessionIDValidatorFunc
+	ErrInvalidSessionID = errors.New("Invalid session id")
+
+	// ErrWeakSessionID is returned when the session ids do not have the minimum entropy
+	ErrWeakSessionID = errors.New("Session id is too weak")
+
+	// ErrInvalidSecureCookie is returned by SetProvider when the cookie name has the __Secure- prefix
+	// but the cookie is not secure
+	ErrInvalidSecureCookie = errors.New("Cookie with __Secure- prefix must be secure")
+
+	// ErrInvalidHostCookie is returned by SetProvider when the cookie name has the __Host- prefix
+	// but the cookie is not secure, its path is not "/" or it has a domain
+	ErrInvalidHostCookie = errors.New("Cookie with __Host- prefix must be secure, with path \"/\" and without domain")
+
+	// ErrUnknownSessionCodec is returned when the session codec is not registered
+	ErrUnknownSessionCodec = errors.New("Unknown session codec")
+
+	// ErrInvalidSessionFormat is returned when the format header of the session data is not supported
+	ErrInvalidSessionFormat = errors.New("Invalid session data format")
+
+	// ErrStoreReleased is returned when a store is used after it has been saved
+	ErrStoreReleased = errors.New("Store has been released")
+
+	// ErrValueNotFound is returned by Store.Bind and GetAs when the key does not exist
+	ErrValueNotFound = errors.New("Session value not found")
+
+	// ErrValueType is returned by Store.Bind and GetAs when the value could not be converted
+	ErrValueType = errors.New("Session value could not be converted")
+
+	// ErrSessionNotFound must be returned by the providers
+	// when the given session id does not exist
+	ErrSessionNotFound = errors.New("Session not found")
+
+	// ErrConcurrentModification is returned by Save when the session
+	// has been saved by another request since it was loaded
+	ErrConcurrentModification = errors.New("Session was modified concurrently")
+
+	// ErrSessionLocked is returned when the lock of a session is held by another owner
+	ErrSessionLocked = errors.New("Session is locked")
+
+	// ErrLockNotSupported is returned when the provider does not implement Locker
+	ErrLockNotSupported = errors.New("Session provider does not support locks")
+
+	// ErrUserIndexNotSupported is returned when the provider does not implement UserIndexer
+	ErrUserIndexNotSupported = errors.New("Session provider does not support user index")
+
+	// ErrScanNotSupported is returned when the provider does not implement Scanner
+	ErrScanNotSupported = errors.New("Session provider does not support scan")
 )
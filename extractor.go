@@ -0,0 +1,272 @@
+package session
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+var bearerPrefix = []byte("Bearer ")
+
+// Extractor reads the session id sent by the client from a specific place of the request,
+// and defines how the session id is written back to the client
+type Extractor interface {
+	// Extract returns the session id sent by the client, or nil if it's not found
+	Extract(ctx *fasthttp.RequestCtx) []byte
+
+	// Set sends the given session id to the client, with the given expiration
+	Set(ctx *fasthttp.RequestCtx, id []byte, expiration time.Duration)
+
+	// Delete removes the session id from the client
+	Delete(ctx *fasthttp.RequestCtx)
+}
+
+type cookieExtractor struct {
+	config *Config
+	cookie *cookie
+}
+
+type headerExtractor struct {
+	name string
+}
+
+type bearerExtractor struct {
+	responseHeader string
+}
+
+type queryExtractor struct {
+	name string
+}
+
+type formExtractor struct {
+	name string
+}
+
+type pathExtractor struct {
+	index int
+}
+
+type funcExtractor struct {
+	extract func(*fasthttp.RequestCtx) []byte
+	set     func(*fasthttp.RequestCtx, []byte, time.Duration)
+	delete  func(*fasthttp.RequestCtx)
+}
+
+// CookieExtractor returns an extractor of the session id from the session cookie,
+// configured by CookieName, Domain, the other Cookie settings, Secure and IsSecureFunc
+func CookieExtractor() Extractor {
+	return new(cookieExtractor)
+}
+
+// HeaderExtractor returns an extractor of the session id from the given http header,
+// which is written back in the same response header
+func HeaderExtractor(name string) Extractor {
+	return &headerExtractor{name: name}
+}
+
+// BearerExtractor returns an extractor of the session id from the Authorization header
+// with the Bearer scheme
+//
+// The session id is written back in the given response header, or it's not written if empty
+func BearerExtractor(responseHeader string) Extractor {
+	return &bearerExtractor{responseHeader: responseHeader}
+}
+
+// QueryExtractor returns an extractor of the session id from the given url query argument,
+// which is not written back
+func QueryExtractor(name string) Extractor {
+	return &queryExtractor{name: name}
+}
+
+// FormExtractor returns an extractor of the session id from the given field
+// of the url encoded or multipart form body, which is not written back
+func FormExtractor(name string) Extractor {
+	return &formExtractor{name: name}
+}
+
+// PathExtractor returns an extractor of the session id from the path segment at the given index,
+// starting from 0, which is not written back
+func PathExtractor(index int) Extractor {
+	return &pathExtractor{index: index}
+}
+
+// FuncExtractor returns an extractor with custom functions,
+// the set and delete functions could be nil if the session id is not written back
+func FuncExtractor(extract func(*fasthttp.RequestCtx) []byte, set func(*fasthttp.RequestCtx, []byte, time.Duration), delete func(*fasthttp.RequestCtx)) Extractor {
+	return &funcExtractor{extract: extract, set: set, delete: delete}
+}
+
+// newExtractors returns the extractors of the given config, bound to the given session
+//
+// If the extractors are not configured, they are built from the cookie, http header
+// and url query settings, with that precedence
+func newExtractors(s *Session) []Extractor {
+	cfg := &s.config
+
+	extractors := cfg.Extractors
+	if len(extractors) == 0 {
+		extractors = []Extractor{CookieExtractor()}
+
+		if cfg.SessionIDInHTTPHeader {
+			extractors = append(extractors, HeaderExtractor(cfg.SessionNameInHTTPHeader))
+		}
+
+		if cfg.SessionIDInURLQuery {
+			extractors = append(extractors, QueryExtractor(cfg.SessionNameInURLQuery), FormExtractor(cfg.SessionNameInURLQuery))
+		}
+	}
+
+	result := make([]Extractor, len(extractors))
+
+	for i, extractor := range extractors {
+		result[i] = bindExtractor(s, extractor)
+	}
+
+	return result
+}
+
+// bindExtractor returns the given extractor bound to the given session
+func bindExtractor(s *Session, extractor Extractor) Extractor {
+	if _, ok := extractor.(*cookieExtractor); ok {
+		return &cookieExtractor{config: &s.config, cookie: s.cookie}
+	}
+
+	return extractor
+}
+
+// extractorUserValueKey is the key of the request user value
+// where the extractor which found the session id of each sessions manager is kept
+type extractorUserValueKey struct {
+	session *Session
+}
+
+// responseExtractors returns the extractors which write back the session id to the client,
+// which is the one that found it in the request if any
+func (s *Session) responseExtractors(ctx *fasthttp.RequestCtx) []Extractor {
+	if s.writeAllExtractors {
+		return s.extractors
+	}
+
+	if extractor, ok := ctx.UserValue(extractorUserValueKey{session: s}).(Extractor); ok {
+		return []Extractor{extractor}
+	}
+
+	return s.defaultExtractors
+}
+
+func (e *cookieExtractor) Extract(ctx *fasthttp.RequestCtx) []byte {
+	return e.cookie.get(ctx, e.config.CookieName)
+}
+
+func (e *cookieExtractor) Set(ctx *fasthttp.RequestCtx, id []byte, expiration time.Duration) {
+	e.cookie.set(ctx, e.config.CookieName, id, expiration, e.config.cookieAttrs(ctx))
+}
+
+func (e *cookieExtractor) Delete(ctx *fasthttp.RequestCtx) {
+	e.cookie.delete(ctx, e.config.CookieName, e.config.cookieAttrs(ctx))
+}
+
+func (e *headerExtractor) Extract(ctx *fasthttp.RequestCtx) []byte {
+	return ctx.Request.Header.Peek(e.name)
+}
+
+func (e *headerExtractor) Set(ctx *fasthttp.RequestCtx, id []byte, expiration time.Duration) {
+	ctx.Request.Header.SetBytesV(e.name, id)
+	ctx.Response.Header.SetBytesV(e.name, id)
+}
+
+func (e *headerExtractor) Delete(ctx *fasthttp.RequestCtx) {
+	ctx.Request.Header.Del(e.name)
+	ctx.Response.Header.Del(e.name)
+}
+
+func (e *bearerExtractor) Extract(ctx *fasthttp.RequestCtx) []byte {
+	auth := ctx.Request.Header.Peek(fasthttp.HeaderAuthorization)
+	if len(auth) <= len(bearerPrefix) || !bytes.EqualFold(auth[:len(bearerPrefix)], bearerPrefix) {
+		return nil
+	}
+
+	return auth[len(bearerPrefix):]
+}
+
+func (e *bearerExtractor) Set(ctx *fasthttp.RequestCtx, id []byte, expiration time.Duration) {
+	if e.responseHeader != "" {
+		ctx.Response.Header.SetBytesV(e.responseHeader, id)
+	}
+}
+
+func (e *bearerExtractor) Delete(ctx *fasthttp.RequestCtx) {
+	ctx.Request.Header.Del(fasthttp.HeaderAuthorization)
+
+	if e.responseHeader != "" {
+		ctx.Response.Header.Del(e.responseHeader)
+	}
+}
+
+func (e *queryExtractor) Extract(ctx *fasthttp.RequestCtx) []byte {
+	return ctx.QueryArgs().Peek(e.name)
+}
+
+func (e *queryExtractor) Set(ctx *fasthttp.RequestCtx, id []byte, expiration time.Duration) {}
+
+func (e *queryExtractor) Delete(ctx *fasthttp.RequestCtx) {}
+
+func (e *formExtractor) Extract(ctx *fasthttp.RequestCtx) []byte {
+	if v := ctx.PostArgs().Peek(e.name); len(v) > 0 {
+		return v
+	}
+
+	form, err := ctx.MultipartForm()
+	if err != nil || len(form.Value[e.name]) == 0 {
+		return nil
+	}
+
+	return []byte(form.Value[e.name][0])
+}
+
+func (e *formExtractor) Set(ctx *fasthttp.RequestCtx, id []byte, expiration time.Duration) {}
+
+func (e *formExtractor) Delete(ctx *fasthttp.RequestCtx) {}
+
+func (e *pathExtractor) Extract(ctx *fasthttp.RequestCtx) []byte {
+	path := ctx.Path()
+	if len(path) > 0 && path[0] == '/' {
+		path = path[1:]
+	}
+
+	for i := 0; i < e.index; i++ {
+		n := bytes.IndexByte(path, '/')
+		if n < 0 {
+			return nil
+		}
+
+		path = path[n+1:]
+	}
+
+	if n := bytes.IndexByte(path, '/'); n >= 0 {
+		path = path[:n]
+	}
+
+	return path
+}
+
+func (e *pathExtractor) Set(ctx *fasthttp.RequestCtx, id []byte, expiration time.Duration) {}
+
+func (e *pathExtractor) Delete(ctx *fasthttp.RequestCtx) {}
+
+func (e *funcExtractor) Extract(ctx *fasthttp.RequestCtx) []byte {
+	return e.extract(ctx)
+}
+
+func (e *funcExtractor) Set(ctx *fasthttp.RequestCtx, id []byte, expiration time.Duration) {
+	if e.set != nil {
+		e.set(ctx, id, expiration)
+	}
+}
+
+func (e *funcExtractor) Delete(ctx *fasthttp.RequestCtx) {
+	if e.delete != nil {
+		e.delete(ctx)
+	}
+}
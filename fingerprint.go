@@ -0,0 +1,115 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net"
+
+	"github.com/valyala/fasthttp"
+)
+
+// FingerprintPolicy is the action taken by Get when the fingerprint of the client
+// does not match the one recorded when the session was created
+type FingerprintPolicy int
+
+const (
+	// FingerprintAllow keeps the session
+	FingerprintAllow FingerprintPolicy = iota
+
+	// FingerprintLog keeps the session and logs the mismatch
+	FingerprintLog
+
+	// FingerprintRegenerate keeps the session data with a new session id
+	FingerprintRegenerate
+
+	// FingerprintDestroy destroys the session and starts a new empty one
+	FingerprintDestroy
+)
+
+// UserAgentFingerprint returns the User-Agent of the client as fingerprint
+func UserAgentFingerprint(ctx *fasthttp.RequestCtx) []byte {
+	return ctx.UserAgent()
+}
+
+// IPPrefixFingerprint returns a fingerprint func which returns the network of the client IP,
+// with the given prefix lengths for the IPv4 and IPv6 addresses (e.g. 24 and 64)
+func IPPrefixFingerprint(ipv4PrefixLen, ipv6PrefixLen int) func(*fasthttp.RequestCtx) []byte {
+	ipv4Mask := net.CIDRMask(ipv4PrefixLen, 8*net.IPv4len)
+	ipv6Mask := net.CIDRMask(ipv6PrefixLen, 8*net.IPv6len)
+
+	return func(ctx *fasthttp.RequestCtx) []byte {
+		ip := ctx.RemoteIP()
+
+		if ipv4 := ip.To4(); ipv4 != nil {
+			return ipv4.Mask(ipv4Mask)
+		}
+
+		return ip.Mask(ipv6Mask)
+	}
+}
+
+// CombineFingerprints returns a fingerprint func which combines the fingerprints of the given funcs,
+// so the session is bound to all of them
+func CombineFingerprints(funcs ...func(*fasthttp.RequestCtx) []byte) func(*fasthttp.RequestCtx) []byte {
+	return func(ctx *fasthttp.RequestCtx) []byte {
+		var fingerprint []byte
+
+		for _, fn := range funcs {
+			value := fn(ctx)
+
+			fingerprint = append(fingerprint, byte(len(value)>>8), byte(len(value)))
+			fingerprint = append(fingerprint, value...)
+		}
+
+		return fingerprint
+	}
+}
+
+// fingerprint returns the hash of the client fingerprint,
+// so the client data is not kept in the session
+func (s *Session) fingerprint(ctx *fasthttp.RequestCtx) string {
+	sum := sha256.Sum256(s.config.FingerprintFunc(ctx))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// checkFingerprint records the client fingerprint in the store if it's not recorded yet,
+// otherwise it applies the FingerprintPolicy if it does not match the current client
+func (s *Session) checkFingerprint(c context.Context, p *sessionProvider, ctx *fasthttp.RequestCtx, store *Store) error {
+	fingerprint := s.fingerprint(ctx)
+
+	if store.fingerprint == fingerprint {
+		return nil
+	}
+
+	if store.fingerprint == "" {
+		// New session, or stored before recording the fingerprint
+		store.fingerprint = fingerprint
+		store.modified = store.modified || !store.isNew
+
+		return nil
+	}
+
+	switch s.config.FingerprintPolicy {
+	case FingerprintLog:
+		s.log.Printf("session fingerprint mismatch from %s", ctx.RemoteIP())
+	case FingerprintRegenerate:
+		if err := s.RegenerateStoreContext(c, ctx, store); err != nil {
+			return err
+		}
+
+		store.fingerprint = fingerprint
+		store.modified = true
+	case FingerprintDestroy:
+		runHook(s.config.Hooks.OnDestroy, ctx, store.sessionID, store)
+
+		if err := s.renew(c, p, ctx, store); err != nil {
+			return err
+		}
+
+		store.fingerprint = fingerprint
+	}
+
+	return nil
+}
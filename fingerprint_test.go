@@ -0,0 +1,165 @@
+package session
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func newFingerprintRequestCtx(ip string, userAgent string) *fasthttp.RequestCtx {
+	req := new(fasthttp.Request)
+	req.Header.SetUserAgent(userAgent)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Init(req, &net.TCPAddr{IP: net.ParseIP(ip)}, nil)
+
+	return ctx
+}
+
+func TestUserAgentFingerprint(t *testing.T) {
+	ctx := newFingerprintRequestCtx("1.2.3.4", "agent")
+
+	if v := UserAgentFingerprint(ctx); string(v) != "agent" {
+		t.Errorf("UserAgentFingerprint() == %s, want %s", v, "agent")
+	}
+}
+
+func TestIPPrefixFingerprint(t *testing.T) {
+	fn := IPPrefixFingerprint(24, 64)
+
+	testCases := []struct {
+		ip1, ip2 string
+		equal    bool
+	}{
+		{"1.2.3.4", "1.2.3.200", true},
+		{"1.2.3.4", "1.2.4.4", false},
+		{"2001:db8::1", "2001:db8::ffff", true},
+		{"2001:db8::1", "2001:db9::1", false},
+	}
+
+	for _, tc := range testCases {
+		v1 := fn(newFingerprintRequestCtx(tc.ip1, ""))
+		v2 := fn(newFingerprintRequestCtx(tc.ip2, ""))
+
+		if bytes.Equal(v1, v2) != tc.equal {
+			t.Errorf("IPPrefixFingerprint() of %s and %s equal == %v, want %v", tc.ip1, tc.ip2, !tc.equal, tc.equal)
+		}
+	}
+}
+
+func TestCombineFingerprints(t *testing.T) {
+	fn := CombineFingerprints(UserAgentFingerprint, IPPrefixFingerprint(24, 64))
+
+	v1 := fn(newFingerprintRequestCtx("1.2.3.4", "agent"))
+	v2 := fn(newFingerprintRequestCtx("1.2.3.5", "agent"))
+	v3 := fn(newFingerprintRequestCtx("1.2.3.4", "other"))
+
+	if !bytes.Equal(v1, v2) {
+		t.Error("CombineFingerprints() must be equal for the same fingerprints")
+	}
+
+	if bytes.Equal(v1, v3) {
+		t.Error("CombineFingerprints() must differ when any fingerprint differs")
+	}
+}
+
+func TestSession_Fingerprint(t *testing.T) {
+	testCases := []struct {
+		policy      FingerprintPolicy
+		keepID      bool
+		keepData    bool
+		regenerated bool
+		destroyed   bool
+	}{
+		{policy: FingerprintAllow, keepID: true, keepData: true},
+		{policy: FingerprintLog, keepID: true, keepData: true},
+		{policy: FingerprintRegenerate, keepID: false, keepData: true, regenerated: true},
+		{policy: FingerprintDestroy, keepID: false, keepData: false, destroyed: true},
+	}
+
+	for _, tc := range testCases {
+		logOutput := new(bytes.Buffer)
+
+		s := New(Config{
+			FingerprintFunc:   UserAgentFingerprint,
+			FingerprintPolicy: tc.policy,
+			Logger:            log.New(logOutput, "test", log.Flags()),
+		})
+		provider := new(mockProvider)
+
+		if err := s.SetProvider(provider); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		id := "asd2324nasd2324nasd2324nasd2324n"
+
+		// New session
+		ctx := newFingerprintRequestCtx("1.2.3.4", "agent")
+		ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+		store, err := s.Get(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if store.fingerprint == "" {
+			t.Fatal("The fingerprint is not recorded")
+		}
+
+		if len(store.GetAll().KV) > 0 {
+			t.Error("The fingerprint is mixed with the store values")
+		}
+
+		store.Set("k", "v")
+
+		provider.data, err = s.encodeStore(store)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// Same client
+		ctx = newFingerprintRequestCtx("1.2.3.4", "agent")
+		ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+		store, err = s.Get(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if v := string(store.GetSessionID()); v != id || provider.destroyed || provider.regeneratedID != nil {
+			t.Errorf("policy %d: The session of the same client must be kept", tc.policy)
+		}
+
+		// Other client
+		ctx = newFingerprintRequestCtx("1.2.3.4", "other")
+		ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+		store, err = s.Get(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if v := string(store.GetSessionID()) == id; v != tc.keepID {
+			t.Errorf("policy %d: session id kept == %v, want %v", tc.policy, v, tc.keepID)
+		}
+
+		if v := store.Get("k") != nil; v != tc.keepData {
+			t.Errorf("policy %d: session data kept == %v, want %v", tc.policy, v, tc.keepData)
+		}
+
+		if v := provider.regeneratedID != nil; v != tc.regenerated {
+			t.Errorf("policy %d: session regenerated == %v, want %v", tc.policy, v, tc.regenerated)
+		}
+
+		if provider.destroyed != tc.destroyed {
+			t.Errorf("policy %d: session destroyed == %v, want %v", tc.policy, provider.destroyed, tc.destroyed)
+		}
+
+		if v := logOutput.Len() > 0; v != (tc.policy == FingerprintLog) {
+			t.Errorf("policy %d: mismatch logged == %v", tc.policy, v)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package session
+
+// AddFlash adds a one-shot message to the given category,
+// which is kept in the session until it's read by Flashes
+//
+// The value must be supported by the session encoding, like the values saved by Set
+func (s *Store) AddFlash(category string, value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isReleased() {
+		return
+	}
+
+	if s.flashes == nil {
+		s.flashes = make(map[string][]interface{})
+	}
+
+	s.flashes[category] = append(s.flashes[category], value)
+	s.modified = true
+}
+
+// Flashes returns the messages of the given category and removes them from the session,
+// so the store must be saved to consume them
+//
+// Returns nil if there are no messages of the category
+func (s *Store) Flashes(category string) []interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isReleased() {
+		return nil
+	}
+
+	flashes, ok := s.flashes[category]
+	if !ok {
+		return nil
+	}
+
+	delete(s.flashes, category)
+	s.modified = true
+
+	return flashes
+}
+
+// flashesEnvelope returns the flash messages to be encoded in the envelope
+//
+// The store lock must be held
+func (s *Store) flashesEnvelope() map[string]interface{} {
+	dst := make(map[string]interface{}, len(s.flashes))
+
+	for category, flashes := range s.flashes {
+		dst[category] = flashes
+	}
+
+	return dst
+}
+
+// openFlashes sets the flash messages of the decoded envelope
+//
+// The store lock must be held
+func (s *Store) openFlashes(src map[string]interface{}) {
+	s.flashes = make(map[string][]interface{}, len(src))
+
+	for category, v := range src {
+		if flashes, ok := v.([]interface{}); ok && len(flashes) > 0 {
+			s.flashes[category] = flashes
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStore_Flashes(t *testing.T) {
+	store := NewStore()
+
+	if v := store.Flashes("info"); v != nil {
+		t.Errorf("Store.Flashes() without messages == %v, want nil", v)
+	}
+
+	if store.IsModified() {
+		t.Error("Store.IsModified() after reading no messages == true, want false")
+	}
+
+	store.AddFlash("info", "a")
+	store.AddFlash("info", "b")
+	store.AddFlash("error", "c")
+
+	if !store.IsModified() || store.isEmpty() {
+		t.Error("Store with flash messages must be modified and not empty")
+	}
+
+	if v := len(store.GetAll().KV); v != 0 {
+		t.Errorf("Store values == %d, want %d", v, 0)
+	}
+
+	clone := store.Clone()
+
+	if v := store.Flashes("info"); !reflect.DeepEqual(v, []interface{}{"a", "b"}) {
+		t.Errorf("Store.Flashes() == %v, want %v", v, []interface{}{"a", "b"})
+	}
+
+	if v := store.Flashes("info"); v != nil {
+		t.Errorf("Store.Flashes() after reading == %v, want nil", v)
+	}
+
+	if v := clone.Flashes("info"); !reflect.DeepEqual(v, []interface{}{"a", "b"}) {
+		t.Errorf("Store.Clone().Flashes() == %v, want %v", v, []interface{}{"a", "b"})
+	}
+
+	store.Reset()
+
+	if v := store.Flashes("error"); v != nil {
+		t.Errorf("Store.Flashes() after reset == %v, want nil", v)
+	}
+}
+
+func TestSession_encodeDecodeStoreFlashes(t *testing.T) {
+	for _, cfg := range []Config{
+		{EncodeFunc: MSGPEncode, DecodeFunc: MSGPDecode},
+		{EncodeFunc: Base64Encode, DecodeFunc: Base64Decode},
+	} {
+		s := New(cfg)
+
+		store := NewStore()
+		store.Set("k", "v")
+		store.AddFlash("info", "a")
+		store.AddFlash("info", int64(1))
+
+		data, err := s.encodeStore(store)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		result := NewStore()
+
+		if err := s.decodeStore(result, data); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if v := result.GetAll().KV; len(v) != 1 || v["k"] != "v" {
+			t.Errorf("Store values == %v, want only %s", v, "k")
+		}
+
+		if v := result.Flashes("info"); !reflect.DeepEqual(v, []interface{}{"a", int64(1)}) {
+			t.Errorf("Store.Flashes() == %v, want %v", v, []interface{}{"a", int64(1)})
+		}
+
+		if !result.IsModified() {
+			t.Error("Store.IsModified() after reading the messages == false, want true")
+		}
+	}
+}
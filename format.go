@@ -0,0 +1,147 @@
+package session
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Names of the built-in session codecs
+const (
+	// SessionCodecMSGP encodes the session data with MessagePack, see MSGPEncode
+	SessionCodecMSGP = "msgp"
+
+	// SessionCodecBase64 encodes the session data with MessagePack and base64, see Base64Encode
+	SessionCodecBase64 = "base64"
+
+	// SessionCodecJSON encodes the session data with JSON, see JSONEncode
+	SessionCodecJSON = "json"
+
+	// SessionCodecGob encodes the session data with gob, see GobEncode
+	SessionCodecGob = "gob"
+
+	// SessionCodecCBOR encodes the session data with CBOR, see CBOREncode
+	SessionCodecCBOR = "cbor"
+)
+
+// SessionCodec is an encoding of the session data registered with RegisterSessionCodec
+type SessionCodec struct {
+	// Name identifies the codec in Config.SessionCodec
+	Name string
+
+	// ID identifies the codec in the format header of the encoded data,
+	// so it must never change once the codec is used
+	ID byte
+
+	// Encode serializes the session data
+	Encode func(src Dict) ([]byte, error)
+
+	// Decode unserializes the session data
+	Decode func(dst *Dict, src []byte) error
+}
+
+var sessionCodecs = struct {
+	lock   sync.RWMutex
+	byName map[string]SessionCodec
+	byID   map[byte]SessionCodec
+}{
+	byName: make(map[string]SessionCodec),
+	byID:   make(map[byte]SessionCodec),
+}
+
+func init() {
+	RegisterSessionCodec(SessionCodec{Name: SessionCodecMSGP, ID: 'm', Encode: MSGPEncode, Decode: MSGPDecode})
+	RegisterSessionCodec(SessionCodec{Name: SessionCodecBase64, ID: 'b', Encode: Base64Encode, Decode: Base64Decode})
+	RegisterSessionCodec(SessionCodec{Name: SessionCodecJSON, ID: 'j', Encode: JSONEncode, Decode: JSONDecode})
+	RegisterSessionCodec(SessionCodec{Name: SessionCodecGob, ID: 'g', Encode: GobEncode, Decode: GobDecode})
+	RegisterSessionCodec(SessionCodec{Name: SessionCodecCBOR, ID: 'c', Encode: CBOREncode, Decode: CBORDecode})
+}
+
+// RegisterSessionCodec makes a session codec available by its name in Config.SessionCodec,
+// and by its id to decode the data encoded with it
+//
+// It panics if the codec is not complete, or if its name or id is already registered
+func RegisterSessionCodec(codec SessionCodec) {
+	if codec.Name == "" || codec.Encode == nil || codec.Decode == nil {
+		panic("session: RegisterSessionCodec codec must have a name, an Encode and a Decode func")
+	}
+
+	sessionCodecs.lock.Lock()
+	defer sessionCodecs.lock.Unlock()
+
+	if _, ok := sessionCodecs.byName[codec.Name]; ok {
+		panic("session: RegisterSessionCodec called twice for codec " + codec.Name)
+	}
+
+	if registered, ok := sessionCodecs.byID[codec.ID]; ok {
+		panic(fmt.Sprintf("session: RegisterSessionCodec id %q of codec %s is used by codec %s", codec.ID, codec.Name, registered.Name))
+	}
+
+	sessionCodecs.byName[codec.Name] = codec
+	sessionCodecs.byID[codec.ID] = codec
+}
+
+// LookupSessionCodec returns the registered session codec with the given name
+func LookupSessionCodec(name string) (SessionCodec, bool) {
+	sessionCodecs.lock.RLock()
+	defer sessionCodecs.lock.RUnlock()
+
+	codec, ok := sessionCodecs.byName[name]
+
+	return codec, ok
+}
+
+func lookupSessionCodecID(id byte) (SessionCodec, bool) {
+	sessionCodecs.lock.RLock()
+	defer sessionCodecs.lock.RUnlock()
+
+	codec, ok := sessionCodecs.byID[id]
+
+	return codec, ok
+}
+
+// encodeFormat encodes the given data with the given codec,
+// prefixed with the format header which identifies it
+func encodeFormat(codec SessionCodec, src Dict) ([]byte, error) {
+	data, err := codec.Encode(src)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := make([]byte, 0, formatHeaderLen+len(data))
+	dst = append(dst, formatMagic, formatVersion, codec.ID)
+
+	return append(dst, data...), nil
+}
+
+// hasFormatHeader returns whether the given data is prefixed with a format header,
+// otherwise it has been encoded without a registered codec
+func hasFormatHeader(src []byte) bool {
+	return len(src) >= formatHeaderLen && src[0] == formatMagic
+}
+
+// decodeFormat decodes the given data with the codec identified by its format header
+func decodeFormat(dst *Dict, src []byte) error {
+	if src[1] != formatVersion {
+		return fmt.Errorf("%w: version %q", ErrInvalidSessionFormat, src[1])
+	}
+
+	codec, ok := lookupSessionCodecID(src[2])
+	if !ok {
+		return fmt.Errorf("%w: id %q", ErrUnknownSessionCodec, src[2])
+	}
+
+	return codec.Decode(dst, src[formatHeaderLen:])
+}
+
+// validateSessionCodec checks that the session codec is registered
+func (c *Config) validateSessionCodec() error {
+	if c.SessionCodec == "" {
+		return nil
+	}
+
+	if _, ok := LookupSessionCodec(c.SessionCodec); !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSessionCodec, c.SessionCodec)
+	}
+
+	return nil
+}
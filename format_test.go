@@ -0,0 +1,262 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestSession_encodeDecodeStoreSessionCodecs(t *testing.T) {
+	codecs := []string{SessionCodecMSGP, SessionCodecBase64, SessionCodecJSON, SessionCodecGob, SessionCodecCBOR}
+
+	for _, name := range codecs {
+		s := New(Config{SessionCodec: name})
+
+		store := NewStore()
+		store.Set("k", "v")
+		store.Set("n", 1)
+		store.AddFlash("info", "saved")
+		store.userID = "user1"
+
+		data, err := s.encodeStore(store)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		codec, _ := LookupSessionCodec(name)
+
+		if !hasFormatHeader(data) || data[2] != codec.ID {
+			t.Errorf("%s: the encoded data is not prefixed with the format header: %q", name, data)
+		}
+
+		// Decoded by the codec of the header, whatever the configured codec is
+		for _, other := range codecs {
+			result := NewStore()
+
+			if err := New(Config{SessionCodec: other}).decodeStore(result, data); err != nil {
+				t.Fatalf("%s decoded with %s: unexpected error: %v", name, other, err)
+			}
+
+			if v := result.Get("k"); v != "v" {
+				t.Errorf("%s decoded with %s: Store.Get() == %v, want %v", name, other, v, "v")
+			}
+
+			if v, err := GetAs[int](result, "n"); err != nil || v != 1 {
+				t.Errorf("%s decoded with %s: GetAs[int]() == (%v, %v), want (%v, %v)", name, other, v, err, 1, nil)
+			}
+
+			if v := result.Flashes("info"); len(v) != 1 || v[0] != "saved" {
+				t.Errorf("%s decoded with %s: Store.Flashes() == %v, want [%s]", name, other, v, "saved")
+			}
+
+			if v := result.UserID(); v != "user1" {
+				t.Errorf("%s decoded with %s: Store.UserID() == %s, want %s", name, other, v, "user1")
+			}
+		}
+	}
+}
+
+func TestSession_encodeDecodeStorePutGetAs(t *testing.T) {
+	type value struct {
+		Name    string
+		Data    []byte
+		Count   int
+		Expires time.Time
+		Tags    map[string]string
+	}
+
+	want := value{
+		Name:    "foo",
+		Data:    []byte{0, 1, 2},
+		Count:   -1,
+		Expires: time.Unix(0, time.Now().UnixNano()).UTC(),
+		Tags:    map[string]string{"k": "v"},
+	}
+
+	sessionCodecs.lock.RLock()
+	names := make([]string, 0, len(sessionCodecs.byName))
+	for name := range sessionCodecs.byName {
+		names = append(names, name)
+	}
+	sessionCodecs.lock.RUnlock()
+
+	for _, name := range names {
+		s := New(Config{SessionCodec: name})
+
+		store := NewStore()
+
+		if err := store.Put("value", want); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		if err := store.Put("bytes", []byte("bytes")); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		data, err := s.encodeStore(store)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		result := NewStore()
+
+		if err := s.decodeStore(result, data); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		v, err := GetAs[value](result, "value")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		if !v.Expires.Equal(want.Expires) {
+			t.Errorf("%s: GetAs().Expires == %v, want %v", name, v.Expires, want.Expires)
+		}
+
+		v.Expires = want.Expires
+
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("%s: GetAs() == %+v, want %+v", name, v, want)
+		}
+
+		if v, err := GetAs[[]byte](result, "bytes"); err != nil || string(v) != "bytes" {
+			t.Errorf("%s: GetAs[[]byte]() == (%s, %v), want (%s, %v)", name, v, err, "bytes", nil)
+		}
+	}
+}
+
+func TestSession_decodeStoreWithoutHeader(t *testing.T) {
+	store := NewStore()
+	store.Set("k", "v")
+
+	// Encoded before the codecs were registered
+	data, err := New(Config{EncodeFunc: Base64Encode}).encodeStore(store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if hasFormatHeader(data) {
+		t.Fatalf("The data encoded by EncodeFunc is prefixed with the format header: %q", data)
+	}
+
+	result := NewStore()
+
+	if err := New(Config{SessionCodec: SessionCodecJSON}).decodeStore(result, data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := result.Get("k"); v != "v" {
+		t.Errorf("Store.Get() == %v, want %v", v, "v")
+	}
+}
+
+func TestSession_decodeStoreInvalidHeader(t *testing.T) {
+	s := New(Config{})
+
+	testCases := []struct {
+		data []byte
+		err  error
+	}{
+		{data: []byte{formatMagic, formatVersion, 'z', 0}, err: ErrUnknownSessionCodec},
+		{data: []byte{formatMagic, '9', 'j', 0}, err: ErrInvalidSessionFormat},
+	}
+
+	for _, tc := range testCases {
+		if err := s.decodeStore(NewStore(), tc.data); !errors.Is(err, tc.err) {
+			t.Errorf("Session.decodeStore(%q) error == %v, want %v", tc.data, err, tc.err)
+		}
+	}
+}
+
+func TestSession_GetDecodeError(t *testing.T) {
+	logOutput := new(bytes.Buffer)
+
+	s := New(Config{
+		Logger: log.New(logOutput, "", 0),
+	})
+
+	provider := &mockProvider{data: []byte{formatMagic, formatVersion, 'z', 0}}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := "asd2324nasd2324nasd2324nasd2324n"
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	// Handled like a missing session
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !provider.destroyed {
+		t.Error("The undecodable session is not destroyed")
+	}
+
+	if v := store.GetSessionID(); len(v) == 0 || string(v) == id {
+		t.Errorf("Store.GetSessionID() == %s, want a new session id", v)
+	}
+
+	if !store.isNew || len(store.GetAll().KV) != 0 {
+		t.Error("Session.Get() must return a new empty store")
+	}
+
+	if !strings.Contains(logOutput.String(), ErrUnknownSessionCodec.Error()) {
+		t.Errorf("The decode error is not logged: %s", logOutput.String())
+	}
+
+	// Destroy error
+	provider.errDestroy = errors.New("destroy")
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	if _, err := s.Get(ctx); err != provider.errDestroy {
+		t.Errorf("Session.Get() error == %v, want %v", err, provider.errDestroy)
+	}
+}
+
+func TestSession_SetProviderUnknownSessionCodec(t *testing.T) {
+	s := New(Config{SessionCodec: "unknown"})
+
+	if err := s.SetProvider(&mockProvider{}); !errors.Is(err, ErrUnknownSessionCodec) {
+		t.Errorf("Session.SetProvider() error == %v, want %v", err, ErrUnknownSessionCodec)
+	}
+}
+
+func TestRegisterSessionCodec(t *testing.T) {
+	codec := SessionCodec{Name: "test-msgp", ID: 'T', Encode: MSGPEncode, Decode: MSGPDecode}
+
+	RegisterSessionCodec(codec)
+
+	if v, ok := LookupSessionCodec(codec.Name); !ok || v.ID != codec.ID {
+		t.Errorf("LookupSessionCodec() == (%v, %v), want the registered codec", v, ok)
+	}
+
+	invalid := []SessionCodec{
+		codec,
+		{Name: "test-other", ID: codec.ID, Encode: MSGPEncode, Decode: MSGPDecode},
+		{Name: "test-incomplete", ID: 'U', Encode: MSGPEncode},
+	}
+
+	for _, c := range invalid {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterSessionCodec(%s) must panic", c.Name)
+				}
+			}()
+
+			RegisterSessionCodec(c)
+		}()
+	}
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// HookEvent is the information of a session passed to the lifecycle hooks
+type HookEvent struct {
+	// ID is the session id
+	ID []byte
+
+	// NewID is the new session id, only set on OnRegenerate
+	NewID []byte
+
+	// Ctx is the request which triggers the event,
+	// or nil if the event is not triggered by a request (e.g. expired by the provider)
+	Ctx *fasthttp.RequestCtx
+
+	// CreatedAt is the time when the session was created, or zero if it's not known
+	CreatedAt time.Time
+
+	// UserID is the user id bound to the session, or empty if it's not known
+	UserID string
+
+	// Expiration is the expiration of the session, or zero if it's not known
+	Expiration time.Duration
+}
+
+// Hooks are the functions called on the lifecycle events of the sessions,
+// the nil ones are not called
+//
+// The hooks are called synchronously, so they should not block
+type Hooks struct {
+	// OnCreate is called by Get when a new session is created
+	OnCreate func(HookEvent)
+
+	// OnLoad is called by Get when a stored session is loaded
+	OnLoad func(HookEvent)
+
+	// OnSave is called when a session is saved
+	OnSave func(HookEvent)
+
+	// OnRegenerate is called when the id of a session is regenerated
+	OnRegenerate func(HookEvent)
+
+	// OnDestroy is called when a session is destroyed
+	OnDestroy func(HookEvent)
+
+	// OnExpire is called when a session expires, by Get when the AbsoluteTimeout is reached,
+	// and by the providers which implement ExpirationNotifier when they remove an expired session.
+	//
+	// The provider notifications are not triggered by a request,
+	// and they could be called concurrently from the provider goroutines
+	OnExpire func(HookEvent)
+}
+
+// newHookEvent returns the event of the given session id,
+// with the metadata of the given store if it's not nil
+func newHookEvent(ctx *fasthttp.RequestCtx, id []byte, store *Store) HookEvent {
+	event := HookEvent{
+		ID:  append([]byte(nil), id...),
+		Ctx: ctx,
+	}
+
+	if store != nil {
+		event.CreatedAt = store.CreatedAt()
+		event.UserID = store.UserID()
+		event.Expiration = store.GetExpiration()
+	}
+
+	return event
+}
+
+// runHook calls the given hook, if it's set, with the event of the given session id
+func runHook(hook func(HookEvent), ctx *fasthttp.RequestCtx, id []byte, store *Store) {
+	if hook == nil {
+		return
+	}
+
+	hook(newHookEvent(ctx, id, store))
+}
+
+// runRegenerateHook calls the OnRegenerate hook, if it's set, with the old and new session ids
+func (s *Session) runRegenerateHook(ctx *fasthttp.RequestCtx, id, newID []byte, store *Store) {
+	if s.config.Hooks.OnRegenerate == nil {
+		return
+	}
+
+	event := newHookEvent(ctx, id, store)
+	event.NewID = append([]byte(nil), newID...)
+
+	s.config.Hooks.OnRegenerate(event)
+}
+
+// onProviderExpired is the handler of the sessions expired by the provider
+func (s *Session) onProviderExpired(id []byte) {
+	runHook(s.config.Hooks.OnExpire, nil, id, nil)
+}
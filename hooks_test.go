@@ -0,0 +1,178 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+type mockExpirationNotifier struct {
+	mockProvider
+
+	handler func(id []byte)
+}
+
+func (p *mockExpirationNotifier) SetExpirationHandler(handler func(id []byte)) {
+	p.handler = handler
+}
+
+// recordHooks returns hooks which record the received events by name
+func recordHooks(events map[string][]HookEvent) Hooks {
+	record := func(name string) func(HookEvent) {
+		return func(event HookEvent) {
+			events[name] = append(events[name], event)
+		}
+	}
+
+	return Hooks{
+		OnCreate:     record("create"),
+		OnLoad:       record("load"),
+		OnSave:       record("save"),
+		OnRegenerate: record("regenerate"),
+		OnDestroy:    record("destroy"),
+		OnExpire:     record("expire"),
+	}
+}
+
+func TestSession_Hooks(t *testing.T) {
+	events := make(map[string][]HookEvent)
+
+	cfg := NewDefaultConfig()
+	cfg.Hooks = recordHooks(events)
+
+	s := New(cfg)
+
+	if err := s.SetProvider(new(mockProvider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := string(store.GetSessionID())
+
+	if len(events["create"]) != 1 || string(events["create"][0].ID) != id || events["create"][0].Ctx != ctx {
+		t.Errorf("OnCreate events == %v, want one of session %s", events["create"], id)
+	}
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events["save"]) != 1 || string(events["save"][0].ID) != id {
+		t.Errorf("OnSave events == %v, want one of session %s", events["save"], id)
+	}
+
+	if events["save"][0].Expiration != cfg.Expiration {
+		t.Errorf("HookEvent.Expiration == %v, want %v", events["save"][0].Expiration, cfg.Expiration)
+	}
+
+	ctx.Request.Header.SetCookie(cfg.CookieName, id)
+
+	if _, err := s.Get(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events["load"]) != 1 || string(events["load"][0].ID) != id {
+		t.Errorf("OnLoad events == %v, want one of session %s", events["load"], id)
+	}
+
+	if events["load"][0].CreatedAt.IsZero() {
+		t.Error("HookEvent.CreatedAt is zero")
+	}
+
+	if err := s.Regenerate(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events["regenerate"]) != 1 || string(events["regenerate"][0].ID) != id || len(events["regenerate"][0].NewID) == 0 {
+		t.Errorf("OnRegenerate events == %v, want one from session %s", events["regenerate"], id)
+	}
+
+	newID := string(events["regenerate"][0].NewID)
+
+	if err := s.Destroy(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events["destroy"]) != 1 || string(events["destroy"][0].ID) != newID {
+		t.Errorf("OnDestroy events == %v, want one of session %s", events["destroy"], newID)
+	}
+
+	if len(events["expire"]) != 0 {
+		t.Errorf("OnExpire events == %v, want none", events["expire"])
+	}
+}
+
+func TestSession_HooksAbsoluteTimeout(t *testing.T) {
+	events := make(map[string][]HookEvent)
+
+	cfg := NewDefaultConfig()
+	cfg.AbsoluteTimeout = time.Hour
+	cfg.Hooks = recordHooks(events)
+
+	s := New(cfg)
+
+	store := NewStore()
+	store.createdAt = time.Now().Add(-2 * time.Hour)
+
+	data, err := s.encodeStore(store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := s.SetProvider(&mockProvider{data: data}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(cfg.CookieName, "expiredidexpiredidexpiredidexpir")
+
+	if _, err := s.Get(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events["expire"]) != 1 || string(events["expire"][0].ID) != "expiredidexpiredidexpiredidexpir" {
+		t.Errorf("OnExpire events == %v, want one of session %s", events["expire"], "expiredidexpiredidexpiredidexpir")
+	}
+
+	if len(events["create"]) != 1 || string(events["create"][0].ID) == "expiredidexpiredidexpiredidexpir" {
+		t.Errorf("OnCreate events == %v, want one of a new session", events["create"])
+	}
+}
+
+func TestSession_HooksExpirationNotifier(t *testing.T) {
+	provider := new(mockExpirationNotifier)
+
+	if err := New(Config{}).SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if provider.handler != nil {
+		t.Error("The expiration handler is set without OnExpire hook")
+	}
+
+	events := make(map[string][]HookEvent)
+
+	cfg := NewDefaultConfig()
+	cfg.Hooks = recordHooks(events)
+
+	if err := New(cfg).SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if provider.handler == nil {
+		t.Fatal("The expiration handler is not set")
+	}
+
+	provider.handler([]byte("id"))
+
+	if len(events["expire"]) != 1 || string(events["expire"][0].ID) != "id" || events["expire"][0].Ctx != nil {
+		t.Errorf("OnExpire events == %v, want one of session %s without request", events["expire"], "id")
+	}
+}
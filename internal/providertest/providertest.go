@@ -0,0 +1,471 @@
+// Package providertest implements the tests shared by the session providers
+package providertest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/session/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// Env returns the value of the given environment variable,
+// skipping the test if it's not set
+//
+// It's used by the providers which need a running server to be tested
+func Env(t *testing.T, key string) string {
+	t.Helper()
+
+	val := os.Getenv(key)
+	if val == "" {
+		t.Skipf("%s is not set", key)
+	}
+
+	return val
+}
+
+// GetNotFound checks that the provider returns session.ErrSessionNotFound
+// for an unknown session id
+func GetNotFound(t *testing.T, provider session.Provider) {
+	t.Helper()
+
+	data, err := provider.Get([]byte("providertest-unknown-id"))
+	if !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Provider.Get() error == %v, want %v", err, session.ErrSessionNotFound)
+	}
+
+	if len(data) > 0 {
+		t.Errorf("Provider.Get() == %s, want empty", data)
+	}
+}
+
+// StrictSessionID checks that a session manager in strict mode
+// does not adopt an unknown session id sent by the client
+func StrictSessionID(t *testing.T, provider session.Provider) {
+	t.Helper()
+
+	cfg := session.NewDefaultConfig()
+	cfg.StrictSessionID = true
+
+	s := session.New(cfg)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Well-formed, so it's looked up in the provider, but never stored
+	attackerID := "providertestAttackerID0123456789"
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(cfg.CookieName, attackerID)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := string(store.GetSessionID())
+	if id == attackerID {
+		t.Fatal("Session.Get() adopts an unknown session id in strict mode")
+	}
+
+	store.Set("k", "v")
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := provider.Get([]byte(attackerID)); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("The unknown session id is persisted, Provider.Get() error == %v", err)
+	}
+
+	// The generated session id is accepted in the next request
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(cfg.CookieName, id)
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := string(store.GetSessionID()); v != id {
+		t.Errorf("Session.Get() session id == %s, want %s", v, id)
+	}
+
+	if v := store.Get("k"); v != "v" {
+		t.Errorf("Store.Get() == %v, want %v", v, "v")
+	}
+
+	if err := s.Destroy(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// CompareAndSave checks the versioned saves of the provider
+func CompareAndSave(t *testing.T, provider interface {
+	session.Provider
+	session.CompareAndSaver
+}) {
+	t.Helper()
+
+	ctx := context.Background()
+	id := []byte("providertest-cas-id")
+
+	defer provider.Destroy(id)
+
+	if _, _, err := provider.GetVersion(ctx, id); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Fatalf("Provider.GetVersion() error == %v, want %v", err, session.ErrSessionNotFound)
+	}
+
+	version, err := provider.CompareAndSave(ctx, id, []byte("v1"), 0, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if version == 0 {
+		t.Fatal("Provider.CompareAndSave() version == 0, want a stored version")
+	}
+
+	if _, err := provider.CompareAndSave(ctx, id, []byte("v1"), 0, time.Minute); !errors.Is(err, session.ErrConcurrentModification) {
+		t.Errorf("Provider.CompareAndSave() of an existing session error == %v, want %v", err, session.ErrConcurrentModification)
+	}
+
+	data, v, err := provider.GetVersion(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(data) != "v1" || v != version {
+		t.Errorf("Provider.GetVersion() == (%s, %d), want (%s, %d)", data, v, "v1", version)
+	}
+
+	newVersion, err := provider.CompareAndSave(ctx, id, []byte("v2"), version, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if newVersion == version {
+		t.Errorf("Provider.CompareAndSave() version is not changed: %d", newVersion)
+	}
+
+	if _, err := provider.CompareAndSave(ctx, id, []byte("v3"), version, time.Minute); !errors.Is(err, session.ErrConcurrentModification) {
+		t.Errorf("Provider.CompareAndSave() with an old version error == %v, want %v", err, session.ErrConcurrentModification)
+	}
+
+	data, v, err = provider.GetVersion(ctx, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(data) != "v2" || v != newVersion {
+		t.Errorf("Provider.GetVersion() == (%s, %d), want (%s, %d)", data, v, "v2", newVersion)
+	}
+}
+
+// Lock checks the session locks of the provider
+func Lock(t *testing.T, provider session.Locker) {
+	t.Helper()
+
+	ctx := context.Background()
+	id := []byte("providertest-lock-id")
+
+	token, err := provider.Lock(ctx, id, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := provider.Lock(ctx, id, time.Minute); !errors.Is(err, session.ErrSessionLocked) {
+		t.Errorf("Provider.Lock() of a locked session error == %v, want %v", err, session.ErrSessionLocked)
+	}
+
+	// Other token
+	if err := provider.Unlock(ctx, id, token+1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := provider.Lock(ctx, id, time.Minute); !errors.Is(err, session.ErrSessionLocked) {
+		t.Errorf("Provider.Unlock() with other token releases the lock")
+	}
+
+	if err := provider.Unlock(ctx, id, token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newToken, err := provider.Lock(ctx, id, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if newToken <= token {
+		t.Errorf("Provider.Lock() token == %d, want greater than %d", newToken, token)
+	}
+
+	// Expired lock
+	time.Sleep(100 * time.Millisecond)
+
+	token, err = provider.Lock(ctx, id, time.Minute)
+	if err != nil {
+		t.Fatalf("The expired lock is not released, Provider.Lock() error == %v", err)
+	}
+
+	if err := provider.Unlock(ctx, id, token); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// UserIndex checks the index of the sessions by user of the provider
+func UserIndex(t *testing.T, provider interface {
+	session.Provider
+	session.UserIndexer
+}) {
+	t.Helper()
+
+	ctx := context.Background()
+	userID := []byte("providertest-user")
+
+	ids := [][]byte{
+		[]byte("providertest-user-id1"),
+		[]byte("providertest-user-id2"),
+		[]byte("providertest-user-id3"),
+	}
+	expirations := []time.Duration{time.Minute, time.Minute, 50 * time.Millisecond}
+
+	for i, id := range ids {
+		defer provider.Destroy(id)
+
+		if err := provider.Save(id, []byte("data"), expirations[i]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := provider.BindUser(ctx, id, userID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	result, err := provider.UserSessions(ctx, userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result) != len(ids) {
+		t.Errorf("Provider.UserSessions() == %s, want %s", result, ids)
+	}
+
+	// Destroyed and expired sessions
+	if err := provider.Destroy(ids[1]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	result, err = provider.UserSessions(ctx, userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result) != 1 || string(result[0]) != string(ids[0]) {
+		t.Errorf("Provider.UserSessions() == %s, want [%s]", result, ids[0])
+	}
+
+	result, err = provider.UserSessions(ctx, []byte("providertest-other-user"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(result) != 0 {
+		t.Errorf("Provider.UserSessions() of other user == %s, want empty", result)
+	}
+}
+
+// Scan checks the enumeration of the sessions of the provider
+func Scan(t *testing.T, provider interface {
+	session.Provider
+	session.Scanner
+}) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	saved := map[string]int{
+		"providertest-scan-id1": 1,
+		"providertest-scan-id2": 2,
+		"providertest-scan-id3": 3,
+	}
+
+	for id, size := range saved {
+		defer provider.Destroy([]byte(id))
+
+		if err := provider.Save([]byte(id), make([]byte, size), time.Minute); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Expired, but not collected by the GC yet
+	expiredID := []byte("providertest-scan-expired")
+	defer provider.Destroy(expiredID)
+
+	if err := provider.Save(expiredID, []byte("expired"), 50*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	found := make(map[string]session.SessionInfo)
+	cursor := ""
+
+	for i := 0; ; i++ {
+		if i > 100 {
+			t.Fatal("Provider.Scan() does not end")
+		}
+
+		infos, next, err := provider.Scan(ctx, cursor, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, info := range infos {
+			found[string(info.ID)] = info
+		}
+
+		if next == "" {
+			break
+		}
+
+		cursor = next
+	}
+
+	if _, ok := found[string(expiredID)]; ok {
+		t.Errorf("Provider.Scan() returns the expired session %s", expiredID)
+	}
+
+	for id, size := range saved {
+		info, ok := found[id]
+		if !ok {
+			t.Errorf("Provider.Scan() does not return the session %s", id)
+			continue
+		}
+
+		if info.Size != size {
+			t.Errorf("SessionInfo.Size of %s == %d, want %d", id, info.Size, size)
+		}
+
+		if info.ExpiresAt.IsZero() || info.ExpiresAt.After(time.Now().Add(time.Minute)) {
+			t.Errorf("SessionInfo.ExpiresAt of %s == %v, want within a minute", id, info.ExpiresAt)
+		}
+	}
+}
+
+// ExpirationHandler checks that the provider notifies the sessions expired by GC
+func ExpirationHandler(t *testing.T, provider interface {
+	session.Provider
+	session.ExpirationNotifier
+}) {
+	t.Helper()
+
+	id := []byte("providertest-expired-id")
+	defer provider.Destroy(id)
+
+	var expired []string
+
+	provider.SetExpirationHandler(func(id []byte) {
+		expired = append(expired, string(id))
+	})
+	defer provider.SetExpirationHandler(nil)
+
+	if err := provider.Save(id, []byte("data"), time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	if err := provider.GC(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(expired) != 1 || expired[0] != string(id) {
+		t.Errorf("Expired sessions == %v, want [%s]", expired, id)
+	}
+}
+
+// Flashes checks that the flash messages are stored by the provider
+// and consumed once they are read, with each session encoding
+func Flashes(t *testing.T, provider session.Provider) {
+	t.Helper()
+
+	encodings := []struct {
+		name   string
+		encode func(src session.Dict) ([]byte, error)
+		decode func(dst *session.Dict, src []byte) error
+	}{
+		{name: "msgp", encode: session.MSGPEncode, decode: session.MSGPDecode},
+		{name: "base64", encode: session.Base64Encode, decode: session.Base64Decode},
+	}
+
+	for _, enc := range encodings {
+		cfg := session.NewDefaultConfig()
+		cfg.EncodeFunc = enc.encode
+		cfg.DecodeFunc = enc.decode
+
+		s := session.New(cfg)
+
+		if err := s.SetProvider(provider); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		ctx := new(fasthttp.RequestCtx)
+
+		store, err := s.Get(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		id := string(store.GetSessionID())
+
+		store.AddFlash("info", "saved")
+		store.AddFlash("info", "saved again")
+		store.AddFlash("error", "failed")
+
+		if err := s.Save(ctx, store); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// The messages of a category are read once
+		for i, want := range [][]interface{}{{"saved", "saved again"}, nil} {
+			ctx = new(fasthttp.RequestCtx)
+			ctx.Request.Header.SetCookie(cfg.CookieName, id)
+
+			store, err = s.Get(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if v := store.Flashes("info"); !reflect.DeepEqual(v, want) {
+				t.Errorf("%s: Store.Flashes() on request %d == %v, want %v", enc.name, i+1, v, want)
+			}
+
+			if err := s.Save(ctx, store); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		ctx = new(fasthttp.RequestCtx)
+		ctx.Request.Header.SetCookie(cfg.CookieName, id)
+
+		store, err = s.Get(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if v := store.Flashes("error"); !reflect.DeepEqual(v, []interface{}{"failed"}) {
+			t.Errorf("%s: Store.Flashes() of other category == %v, want %v", enc.name, v, []interface{}{"failed"})
+		}
+
+		if err := s.Destroy(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
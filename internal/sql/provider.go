@@ -1,9 +1,12 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
+	"github.com/fasthttp/session/v2"
 	"github.com/savsgio/gotils/strconv"
 )
 
@@ -36,12 +39,21 @@ func NewProvider(cfg ProviderConfig) (*Provider, error) {
 // Returns the number of rows affected by an update, insert, or delete.
 // Not every database or database driver may support this.
 func (p *Provider) Exec(query string, args ...interface{}) (int64, error) {
-	tx, err := p.db.Begin()
+	return p.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a query without returning any rows.
+// The args are for any placeholder parameters in the query.
+//
+// Returns the number of rows affected by an update, insert, or delete.
+// Not every database or database driver may support this.
+func (p *Provider) ExecContext(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
 
-	result, err := tx.Exec(query, args...)
+	result, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		tx.Rollback()
 
@@ -57,6 +69,28 @@ func (p *Provider) Exec(query string, args ...interface{}) (int64, error) {
 	return result.RowsAffected()
 }
 
+// AddColumns adds the given columns to the given table if it does not have them,
+// since it could have been created by a previous version
+//
+// Returns an error to migrate the table manually if a column could not be added
+func (p *Provider) AddColumns(table string, columns []Column) error {
+	for _, column := range columns {
+		rows, err := p.db.Query(fmt.Sprintf("SELECT %s FROM %s WHERE 1=0", column.Name, table))
+		if err == nil {
+			rows.Close()
+			continue
+		}
+
+		for _, query := range column.SQLAdd {
+			if _, err := p.Exec(fmt.Sprintf(query, table)); err != nil {
+				return fmt.Errorf("the column %s could not be added to the table %s, it must be migrated as described in the provider README: %w", column.Name, table, err)
+			}
+		}
+	}
+
+	return nil
+}
+
 // Close closes the database and prevents new queries from starting.
 // Close then waits for all queries that have started processing on the server
 // to finish.
@@ -69,12 +103,21 @@ func (p *Provider) Close() error {
 
 // Get returns the data of the given session id
 func (p *Provider) Get(id []byte) ([]byte, error) {
-	result := p.db.QueryRow(p.config.SQLGet, strconv.B2S(id))
+	return p.GetContext(context.Background(), id)
+}
+
+// GetContext returns the data of the given session id
+//
+// Returns session.ErrSessionNotFound if the session does not exist
+func (p *Provider) GetContext(ctx context.Context, id []byte) ([]byte, error) {
+	result := p.db.QueryRowContext(ctx, p.config.SQLGet, strconv.B2S(id))
 
 	data := []byte("")
 
 	err := result.Scan(&data)
-	if err != nil && err != sql.ErrNoRows {
+	if err == sql.ErrNoRows {
+		return nil, session.ErrSessionNotFound
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -83,15 +126,20 @@ func (p *Provider) Get(id []byte) ([]byte, error) {
 
 // Save saves the session data and expiration from the given session id
 func (p *Provider) Save(id, data []byte, expiration time.Duration) error {
+	return p.SaveContext(context.Background(), id, data, expiration)
+}
+
+// SaveContext saves the session data and expiration from the given session id
+func (p *Provider) SaveContext(ctx context.Context, id, data []byte, expiration time.Duration) error {
 	now := time.Now().UnixNano()
 
-	n, err := p.Exec(p.config.SQLSave, strconv.B2S(data), now, expiration.Nanoseconds(), strconv.B2S(id))
+	n, err := p.ExecContext(ctx, p.config.SQLSave, strconv.B2S(data), now, expiration.Nanoseconds(), strconv.B2S(id))
 	if err != nil {
 		return err
 	}
 
 	if n == 0 { // Not exist
-		_, err = p.Exec(p.config.SQLInsert, strconv.B2S(id), strconv.B2S(data), now, expiration.Nanoseconds())
+		_, err = p.ExecContext(ctx, p.config.SQLInsert, strconv.B2S(id), strconv.B2S(data), now, expiration.Nanoseconds())
 		if err != nil {
 			return err
 		}
@@ -100,18 +148,76 @@ func (p *Provider) Save(id, data []byte, expiration time.Duration) error {
 	return nil
 }
 
+// GetVersion returns the data and the version of the given session id
+//
+// Returns session.ErrSessionNotFound if the session does not exist
+func (p *Provider) GetVersion(ctx context.Context, id []byte) ([]byte, uint64, error) {
+	result := p.db.QueryRowContext(ctx, p.config.SQLGetVersion, strconv.B2S(id))
+
+	data := []byte("")
+	var version int64
+
+	err := result.Scan(&data, &version)
+	if err == sql.ErrNoRows {
+		return nil, 0, session.ErrSessionNotFound
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	return data, uint64(version), nil
+}
+
+// CompareAndSave saves the session data and expiration from the given session id
+// only if its stored version is the given one, and returns the new version
+//
+// Returns session.ErrConcurrentModification if the stored version is another one
+func (p *Provider) CompareAndSave(ctx context.Context, id, data []byte, version uint64, expiration time.Duration) (uint64, error) {
+	now := time.Now().UnixNano()
+
+	n, err := p.ExecContext(ctx, p.config.SQLCompareAndSave, strconv.B2S(data), now, expiration.Nanoseconds(), strconv.B2S(id), int64(version))
+	if err != nil {
+		return 0, err
+	}
+
+	if n > 0 {
+		return version + 1, nil
+	}
+
+	if version != 0 {
+		return 0, session.ErrConcurrentModification
+	}
+
+	// Not stored yet, the insert fails if another request has stored it in the meantime
+	_, err = p.ExecContext(ctx, p.config.SQLInsert, strconv.B2S(id), strconv.B2S(data), now, expiration.Nanoseconds())
+	if err != nil {
+		if _, _, errGet := p.GetVersion(ctx, id); errGet == nil {
+			return 0, session.ErrConcurrentModification
+		}
+
+		return 0, err
+	}
+
+	return 1, nil
+}
+
 // Regenerate updates the session id and expiration with the new session id
 // of the the given current session id
 func (p *Provider) Regenerate(id, newID []byte, expiration time.Duration) error {
+	return p.RegenerateContext(context.Background(), id, newID, expiration)
+}
+
+// RegenerateContext updates the session id and expiration with the new session id
+// of the the given current session id
+func (p *Provider) RegenerateContext(ctx context.Context, id, newID []byte, expiration time.Duration) error {
 	now := time.Now().UnixNano()
 
-	n, err := p.Exec(p.config.SQLRegenerate, strconv.B2S(newID), now, expiration.Nanoseconds(), strconv.B2S(id))
+	n, err := p.ExecContext(ctx, p.config.SQLRegenerate, strconv.B2S(newID), now, expiration.Nanoseconds(), strconv.B2S(id))
 	if err != nil {
 		return err
 	}
 
 	if n == 0 { // Not exist
-		_, err = p.Exec(p.config.SQLInsert, strconv.B2S(newID), "", now, expiration.Nanoseconds())
+		_, err = p.ExecContext(ctx, p.config.SQLInsert, strconv.B2S(newID), "", now, expiration.Nanoseconds())
 		if err != nil {
 			return err
 		}
@@ -120,15 +226,180 @@ func (p *Provider) Regenerate(id, newID []byte, expiration time.Duration) error
 	return nil
 }
 
+// Touch updates the last active time and expiration of the given session id
+// without rewriting its data
+func (p *Provider) Touch(id []byte, expiration time.Duration) error {
+	return p.TouchContext(context.Background(), id, expiration)
+}
+
+// TouchContext updates the last active time and expiration of the given session id
+// without rewriting its data
+func (p *Provider) TouchContext(ctx context.Context, id []byte, expiration time.Duration) error {
+	_, err := p.ExecContext(ctx, p.config.SQLTouch, time.Now().UnixNano(), expiration.Nanoseconds(), strconv.B2S(id))
+
+	return err
+}
+
+// nextLockToken increments the counter of the lock fencing tokens and returns its new value
+func (p *Provider) nextLockToken(ctx context.Context) (int64, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var token int64
+
+	if _, err := tx.ExecContext(ctx, p.config.SQLLockToken); err != nil {
+		tx.Rollback()
+
+		return 0, err
+	}
+
+	// Read in the same transaction, so it's the value set by the increment
+	if err := tx.QueryRowContext(ctx, p.config.SQLLockTokenGet).Scan(&token); err != nil {
+		tx.Rollback()
+
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+
+		return 0, err
+	}
+
+	return token, nil
+}
+
+// Lock acquires the lock of the given session id for the given ttl,
+// and returns a fencing token which is greater each time the lock is acquired
+//
+// The lock is a row of the lock table, and the token is taken from the counter of the lock token table.
+// Returns session.ErrSessionLocked if the lock is held by another owner
+func (p *Provider) Lock(ctx context.Context, id []byte, ttl time.Duration) (uint64, error) {
+	now := time.Now().UnixNano()
+
+	// Release the expired lock
+	if _, err := p.ExecContext(ctx, p.config.SQLLockClean, strconv.B2S(id), now); err != nil {
+		return 0, err
+	}
+
+	token, err := p.nextLockToken(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = p.ExecContext(ctx, p.config.SQLLock, strconv.B2S(id), token, now+ttl.Nanoseconds())
+	if err != nil {
+		var current int64
+		if errGet := p.db.QueryRowContext(ctx, p.config.SQLLockGet, strconv.B2S(id)).Scan(&current); errGet == nil {
+			return 0, session.ErrSessionLocked
+		}
+
+		return 0, err
+	}
+
+	return uint64(token), nil
+}
+
+// Unlock releases the lock of the given session id if it's still held with the given token
+func (p *Provider) Unlock(ctx context.Context, id []byte, token uint64) error {
+	_, err := p.ExecContext(ctx, p.config.SQLUnlock, strconv.B2S(id), int64(token))
+
+	return err
+}
+
+// BindUser adds the given session id to the sessions of the given user id
+func (p *Provider) BindUser(ctx context.Context, id, userID []byte) error {
+	_, err := p.ExecContext(ctx, p.config.SQLBindUser, strconv.B2S(userID), strconv.B2S(id))
+
+	return err
+}
+
+// UserSessions returns the ids of the sessions of the given user id,
+// excluding the expired and destroyed ones
+func (p *Provider) UserSessions(ctx context.Context, userID []byte) ([][]byte, error) {
+	rows, err := p.db.QueryContext(ctx, p.config.SQLUserSessions, strconv.B2S(userID), time.Now().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids [][]byte
+
+	for rows.Next() {
+		var id []byte
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Scan returns up to limit sessions ordered by id after the given cursor,
+// excluding the expired ones, and the cursor of the next ones
+func (p *Provider) Scan(ctx context.Context, cursor string, limit int) ([]session.SessionInfo, string, error) {
+	rows, err := p.db.QueryContext(ctx, p.config.SQLScan, cursor, time.Now().UnixNano(), limit)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var infos []session.SessionInfo
+
+	for rows.Next() {
+		var (
+			info       session.SessionInfo
+			lastActive int64
+			expiration int64
+		)
+
+		if err := rows.Scan(&info.ID, &info.Size, &lastActive, &expiration); err != nil {
+			return nil, "", err
+		}
+
+		info.LastActive = time.Unix(0, lastActive)
+		if expiration > 0 {
+			info.ExpiresAt = time.Unix(0, lastActive+expiration)
+		}
+
+		infos = append(infos, info)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(infos) == limit {
+		next = string(infos[len(infos)-1].ID)
+	}
+
+	return infos, next, nil
+}
+
 // Destroy destroys the session from the given id
 func (p *Provider) Destroy(id []byte) error {
-	_, err := p.Exec(p.config.SQLDestroy, strconv.B2S(id))
+	return p.DestroyContext(context.Background(), id)
+}
+
+// DestroyContext destroys the session from the given id
+func (p *Provider) DestroyContext(ctx context.Context, id []byte) error {
+	_, err := p.ExecContext(ctx, p.config.SQLDestroy, strconv.B2S(id))
 	return err
 }
 
 // Count returns the total of stored sessions
 func (p *Provider) Count() int {
-	row := p.db.QueryRow(p.config.SQLCount)
+	return p.CountContext(context.Background())
+}
+
+// CountContext returns the total of stored sessions
+func (p *Provider) CountContext(ctx context.Context) int {
+	row := p.db.QueryRowContext(ctx, p.config.SQLCount)
 
 	total := 0
 	if err := row.Scan(&total); err != nil {
@@ -143,9 +414,75 @@ func (p *Provider) NeedGC() bool {
 	return true
 }
 
+// SetExpirationHandler sets the function called by GC with the id of each expired session
+func (p *Provider) SetExpirationHandler(handler func(id []byte)) {
+	p.expirationHandler = handler
+}
+
 // GC destroys the expired sessions
 func (p *Provider) GC() error {
-	_, err := p.Exec(p.config.SQLGC, time.Now().UnixNano())
+	return p.GCContext(context.Background())
+}
+
+// expiredIDs returns the ids of the sessions expired at the given time
+func (p *Provider) expiredIDs(ctx context.Context, now int64) ([][]byte, error) {
+	rows, err := p.db.QueryContext(ctx, p.config.SQLGCExpired, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids [][]byte
+
+	for rows.Next() {
+		var id []byte
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// gcExpired destroys the expired sessions one by one, and notifies each destroyed one
+//
+// Each session is destroyed only if it's still expired, since it could have been saved
+// since it was selected, so the notified sessions are exactly the destroyed ones
+func (p *Provider) gcExpired(ctx context.Context, now int64) error {
+	ids, err := p.expiredIDs(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		n, err := p.ExecContext(ctx, p.config.SQLGCDestroy, strconv.B2S(id), now)
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			p.expirationHandler(id)
+		}
+	}
+
+	return nil
+}
+
+// GCContext destroys the expired sessions
+func (p *Provider) GCContext(ctx context.Context) error {
+	now := time.Now().UnixNano()
+
+	if p.expirationHandler == nil {
+		if _, err := p.ExecContext(ctx, p.config.SQLGC, now); err != nil {
+			return err
+		}
+	} else if err := p.gcExpired(ctx, now); err != nil {
+		return err
+	}
+
+	_, err := p.ExecContext(ctx, p.config.SQLLockGC, now)
 
 	return err
 }
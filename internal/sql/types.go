@@ -41,17 +41,44 @@ type ProviderConfig struct {
 	// If d <= 0, connections are reused forever.
 	ConnMaxLifetime time.Duration
 
-	SQLGet        string
-	SQLSave       string
-	SQLRegenerate string
-	SQLDestroy    string
-	SQLCount      string
-	SQLInsert     string
-	SQLGC         string
+	SQLGet            string
+	SQLGetVersion     string
+	SQLSave           string
+	SQLCompareAndSave string
+	SQLTouch          string
+	SQLRegenerate     string
+	SQLDestroy        string
+	SQLCount          string
+	SQLInsert         string
+	SQLGC             string
+	SQLGCExpired      string
+	SQLGCDestroy      string
+	SQLLock           string
+	SQLLockGet        string
+	SQLLockToken      string
+	SQLLockTokenGet   string
+	SQLLockClean      string
+	SQLUnlock         string
+	SQLLockGC         string
+	SQLBindUser       string
+	SQLUserSessions   string
+	SQLScan           string
+}
+
+// Column is a column of the session table added by a later version,
+// so it's added to the tables created by the previous ones
+type Column struct {
+	Name string
+
+	// SQLAdd are the queries which add the column to the table,
+	// formatted with the table name
+	SQLAdd []string
 }
 
 // Provider backend manager
 type Provider struct {
 	config ProviderConfig
 	db     *sql.DB
+
+	expirationHandler func(id []byte)
 }
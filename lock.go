@@ -0,0 +1,63 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Lock acquires the lock of the given session id for the given ttl,
+// and returns a fencing token which is greater each time the lock is acquired
+//
+// If the lock is held by another owner, it waits for it up to the LockWaitTimeout
+// or until the given context is done, otherwise it returns ErrSessionLocked.
+// The lock must be released with Unlock.
+func (s *Session) Lock(c context.Context, id []byte, ttl time.Duration) (uint64, error) {
+	p := s.provider.Load()
+	if p == nil {
+		return 0, ErrNotSetProvider
+	}
+
+	if p.locker == nil {
+		return 0, ErrLockNotSupported
+	}
+
+	deadline := time.Now().Add(s.config.LockWaitTimeout)
+
+	for {
+		pctx, cancel := s.providerContext(c, nil)
+		token, err := p.locker.Lock(pctx, id, ttl)
+		cancel()
+
+		if !errors.Is(err, ErrSessionLocked) || !time.Now().Before(deadline) {
+			return token, err
+		}
+
+		timer := time.NewTimer(lockRetryInterval)
+
+		select {
+		case <-c.Done():
+			timer.Stop()
+
+			return 0, c.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Unlock releases the lock of the given session id if it's still held with the given token
+func (s *Session) Unlock(c context.Context, id []byte, token uint64) error {
+	p := s.provider.Load()
+	if p == nil {
+		return ErrNotSetProvider
+	}
+
+	if p.locker == nil {
+		return ErrLockNotSupported
+	}
+
+	pctx, cancel := s.providerContext(c, nil)
+	defer cancel()
+
+	return p.locker.Unlock(pctx, id, token)
+}
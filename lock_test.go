@@ -0,0 +1,98 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSession_LockErrNotProvider(t *testing.T) {
+	s := New(Config{})
+
+	if _, err := s.Lock(context.Background(), []byte("id"), time.Second); err != ErrNotSetProvider {
+		t.Errorf("Expected error: %v", ErrNotSetProvider)
+	}
+
+	if err := s.Unlock(context.Background(), []byte("id"), 1); err != ErrNotSetProvider {
+		t.Errorf("Expected error: %v", ErrNotSetProvider)
+	}
+}
+
+func TestSession_LockNotSupported(t *testing.T) {
+	s := New(Config{})
+
+	if err := s.SetProvider(new(mockProvider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := s.Lock(context.Background(), []byte("id"), time.Second); err != ErrLockNotSupported {
+		t.Errorf("Expected error: %v", ErrLockNotSupported)
+	}
+
+	if err := s.Unlock(context.Background(), []byte("id"), 1); err != ErrLockNotSupported {
+		t.Errorf("Expected error: %v", ErrLockNotSupported)
+	}
+}
+
+func TestSession_Lock(t *testing.T) {
+	s := New(Config{})
+	provider := new(mockLocker)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := []byte("id")
+
+	token, err := s.Lock(context.Background(), id, time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := s.Lock(context.Background(), id, time.Second); err != ErrSessionLocked {
+		t.Errorf("Session.Lock() error == %v, want %v", err, ErrSessionLocked)
+	}
+
+	if provider.calls != 2 {
+		t.Errorf("Locker.Lock() calls == %d, want %d", provider.calls, 2)
+	}
+
+	if err := s.Unlock(context.Background(), id, token); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	newToken, err := s.Lock(context.Background(), id, time.Second)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if newToken <= token {
+		t.Errorf("Session.Lock() token == %d, want greater than %d", newToken, token)
+	}
+}
+
+func TestSession_LockWait(t *testing.T) {
+	s := New(Config{
+		LockWaitTimeout: 50 * time.Millisecond,
+	})
+	provider := &mockLocker{locked: true}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := s.Lock(context.Background(), []byte("id"), time.Second); err != ErrSessionLocked {
+		t.Errorf("Session.Lock() error == %v, want %v", err, ErrSessionLocked)
+	}
+
+	if provider.calls < 2 {
+		t.Errorf("Locker.Lock() calls == %d, want the lock to be retried", provider.calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.Lock(ctx, []byte("id"), time.Second); err != context.Canceled {
+		t.Errorf("Session.Lock() error == %v, want %v", err, context.Canceled)
+	}
+}
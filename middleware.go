@@ -0,0 +1,97 @@
+package session
+
+import (
+	"context"
+
+	"github.com/valyala/fasthttp"
+)
+
+// storeUserValueKey is the key of the request user value
+// where the loaded store is kept during the request
+//
+// The store of each sessions manager is kept with its own key,
+// and the last loaded one with the key without manager
+type storeUserValueKey struct {
+	session *Session
+}
+
+// FromRequestCtx returns the store loaded for the current request
+// by Session.Get or Session.Middleware
+//
+// If many sessions managers are used in the same request, it returns the last loaded store,
+// so use Session.FromRequestCtx to get the store of each of them.
+// Returns nil if the store is not loaded, or if it has been already saved or destroyed
+func FromRequestCtx(ctx *fasthttp.RequestCtx) *Store {
+	store, _ := ctx.UserValue(storeUserValueKey{}).(*Store)
+
+	return store
+}
+
+// FromRequestCtx returns the store loaded by the sessions manager for the current request
+// by Session.Get or Session.Middleware
+//
+// Returns nil if the store is not loaded, or if it has been already saved or destroyed
+func (s *Session) FromRequestCtx(ctx *fasthttp.RequestCtx) *Store {
+	store, _ := ctx.UserValue(storeUserValueKey{session: s}).(*Store)
+
+	return store
+}
+
+func (s *Session) setRequestCtxStore(ctx *fasthttp.RequestCtx, store *Store) {
+	ctx.SetUserValue(storeUserValueKey{session: s}, store)
+	ctx.SetUserValue(storeUserValueKey{}, store)
+}
+
+func (s *Session) delRequestCtxStore(ctx *fasthttp.RequestCtx) {
+	store := s.FromRequestCtx(ctx)
+	ctx.RemoveUserValue(storeUserValueKey{session: s})
+
+	// The last loaded store could belong to another sessions manager
+	if store != nil && FromRequestCtx(ctx) == store {
+		ctx.RemoveUserValue(storeUserValueKey{})
+	}
+}
+
+// Middleware wraps the given handler, loading the user session before calling it
+// and saving it when it returns
+//
+// The store is available in the handler via Session.FromRequestCtx, FromRequestCtx or Session.Get
+// If the handler saves or destroys the session by itself, the middleware does not save it again
+//
+// If MiddlewareLockTTL is set, the session is locked during the request
+func (s *Session) Middleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if s.config.MiddlewareSkipper != nil && s.config.MiddlewareSkipper(ctx) {
+			next(ctx)
+			return
+		}
+
+		if id := s.getSessionID(ctx); len(id) > 0 && s.config.MiddlewareLockTTL > 0 {
+			id = append([]byte(nil), id...)
+
+			token, err := s.Lock(context.Background(), id, s.config.MiddlewareLockTTL)
+			if err != nil {
+				s.config.MiddlewareLoadErrorHandler(ctx, err)
+				return
+			}
+
+			defer s.Unlock(context.Background(), id, token)
+		}
+
+		if _, err := s.Get(ctx); err != nil {
+			s.config.MiddlewareLoadErrorHandler(ctx, err)
+			return
+		}
+
+		next(ctx)
+
+		store := s.FromRequestCtx(ctx)
+		if store == nil { // Saved or destroyed by the handler
+			return
+		}
+
+		if err := s.Save(ctx, store); err != nil {
+			s.config.MiddlewareSaveErrorHandler(ctx, err)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package session
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestFromRequestCtx(t *testing.T) {
+	s := New(Config{})
+	ctx := new(fasthttp.RequestCtx)
+
+	if store := FromRequestCtx(ctx); store != nil {
+		t.Errorf("FromRequestCtx() == %p, want %v", store, nil)
+	}
+
+	store := NewStore()
+	s.setRequestCtxStore(ctx, store)
+
+	if v := FromRequestCtx(ctx); v != store {
+		t.Errorf("FromRequestCtx() == %p, want %p", v, store)
+	}
+
+	if v := s.FromRequestCtx(ctx); v != store {
+		t.Errorf("Session.FromRequestCtx() == %p, want %p", v, store)
+	}
+
+	s.delRequestCtxStore(ctx)
+
+	if v := FromRequestCtx(ctx); v != nil {
+		t.Errorf("FromRequestCtx() == %p, want %v", v, nil)
+	}
+
+	if v := s.FromRequestCtx(ctx); v != nil {
+		t.Errorf("Session.FromRequestCtx() == %p, want %v", v, nil)
+	}
+}
+
+func TestSession_GetManyManagers(t *testing.T) {
+	s1 := New(Config{CookieName: "session1"})
+	s2 := New(Config{CookieName: "session2"})
+
+	for _, s := range []*Session{s1, s2} {
+		if err := s.SetProvider(new(mockProvider)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+
+	store1, err := s1.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	store2, err := s2.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if store1 == store2 {
+		t.Fatal("Session.Get() of another sessions manager returns the same store")
+	}
+
+	if v := s1.FromRequestCtx(ctx); v != store1 {
+		t.Errorf("Session.FromRequestCtx() == %p, want %p", v, store1)
+	}
+
+	if err := s1.Save(ctx, store1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if store2.IsReleased() {
+		t.Error("The store of another sessions manager is released")
+	}
+
+	if v := s2.FromRequestCtx(ctx); v != store2 {
+		t.Errorf("Session.FromRequestCtx() == %p, want %p", v, store2)
+	}
+
+	if v := FromRequestCtx(ctx); v != store2 {
+		t.Errorf("FromRequestCtx() == %p, want the last loaded store %p", v, store2)
+	}
+
+	if err := s2.Save(ctx, store2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := FromRequestCtx(ctx); v != nil {
+		t.Errorf("FromRequestCtx() after save == %p, want %v", v, nil)
+	}
+}
+
+func TestSession_GetIdempotent(t *testing.T) {
+	s := New(Config{})
+
+	if err := s.SetProvider(new(mockProvider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+
+	store1, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	store2, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if store1 != store2 {
+		t.Errorf("Session.Get() == %p, want %p", store2, store1)
+	}
+
+	if err := s.Save(ctx, store1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := FromRequestCtx(ctx); v != nil {
+		t.Errorf("FromRequestCtx() after save == %p, want %v", v, nil)
+	}
+}
+
+func TestSession_Middleware(t *testing.T) {
+	s := New(Config{})
+
+	if err := s.SetProvider(new(mockProvider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+
+	var store *Store
+
+	handler := s.Middleware(func(ctx *fasthttp.RequestCtx) {
+		store = FromRequestCtx(ctx)
+		if store == nil {
+			t.Fatal("The store is not loaded")
+		}
+
+		store.Set("foo", "bar")
+	})
+	handler(ctx)
+
+	if ctx.Response.Header.PeekCookie(s.config.CookieName) == nil {
+		t.Error("The session is not saved")
+	}
+
+	if FromRequestCtx(ctx) != nil {
+		t.Error("The store is kept in the request after save")
+	}
+}
+
+func TestSession_MiddlewareSavedByHandler(t *testing.T) {
+	s := New(Config{})
+	provider := new(mockProvider)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+
+	handler := s.Middleware(func(ctx *fasthttp.RequestCtx) {
+		store, err := s.Get(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if err := s.Save(ctx, store); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		provider.errSave = errors.New("saved twice")
+	})
+	handler(ctx)
+
+	if ctx.Response.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("Response status code == %d, want %d", ctx.Response.StatusCode(), fasthttp.StatusOK)
+	}
+}
+
+func TestSession_MiddlewareSkipper(t *testing.T) {
+	s := New(Config{
+		MiddlewareSkipper: func(ctx *fasthttp.RequestCtx) bool {
+			return string(ctx.Path()) == "/skip"
+		},
+	})
+
+	if err := s.SetProvider(new(mockProvider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI("/skip")
+
+	called := false
+
+	handler := s.Middleware(func(ctx *fasthttp.RequestCtx) {
+		called = true
+
+		if FromRequestCtx(ctx) != nil {
+			t.Error("The store is loaded in a skipped request")
+		}
+	})
+	handler(ctx)
+
+	if !called {
+		t.Error("The handler is not called")
+	}
+
+	if ctx.Response.Header.PeekCookie(s.config.CookieName) != nil {
+		t.Error("The session is saved in a skipped request")
+	}
+}
+
+func TestSession_MiddlewareErrorHandlers(t *testing.T) {
+	var loadErr, saveErr error
+
+	s := New(Config{
+		MiddlewareLoadErrorHandler: func(ctx *fasthttp.RequestCtx, err error) {
+			loadErr = err
+		},
+		MiddlewareSaveErrorHandler: func(ctx *fasthttp.RequestCtx, err error) {
+			saveErr = err
+		},
+	})
+	provider := &mockProvider{
+		errGet:  errors.New("error from provider get"),
+		errSave: errors.New("error from provider save"),
+	}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	called := false
+	handler := s.Middleware(func(ctx *fasthttp.RequestCtx) {
+		called = true
+	})
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, "asd2324nasd2324nasd2324nasd2324n")
+	handler(ctx)
+
+	if loadErr != provider.errGet {
+		t.Errorf("Load error == %v, want %v", loadErr, provider.errGet)
+	}
+
+	if called {
+		t.Error("The handler is called after a load error")
+	}
+
+	ctx = new(fasthttp.RequestCtx)
+	handler(ctx)
+
+	if saveErr != provider.errSave {
+		t.Errorf("Save error == %v, want %v", saveErr, provider.errSave)
+	}
+}
+
+func TestSession_MiddlewareDefaultErrorHandler(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := log.New(output, "test", log.Flags())
+
+	s := New(Config{
+		Logger: logger,
+	})
+
+	if err := s.SetProvider(&mockProvider{errSave: errors.New("error from provider")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+
+	s.Middleware(func(ctx *fasthttp.RequestCtx) {})(ctx)
+
+	if ctx.Response.StatusCode() != fasthttp.StatusInternalServerError {
+		t.Errorf("Response status code == %d, want %d", ctx.Response.StatusCode(), fasthttp.StatusInternalServerError)
+	}
+
+	if output.Len() == 0 {
+		t.Errorf("the error it not write on log")
+	}
+}
+
+func TestSession_MiddlewareLock(t *testing.T) {
+	var loadErr error
+
+	s := New(Config{
+		MiddlewareLockTTL: time.Second,
+		MiddlewareLoadErrorHandler: func(ctx *fasthttp.RequestCtx, err error) {
+			loadErr = err
+		},
+	})
+	provider := new(mockLocker)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	locked := false
+
+	handler := s.Middleware(func(ctx *fasthttp.RequestCtx) {
+		locked = provider.locked
+	})
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, "asd2324nasd2324nasd2324nasd2324n")
+
+	handler(ctx)
+
+	if !locked {
+		t.Error("The session is not locked during the request")
+	}
+
+	if provider.locked {
+		t.Error("The session is not unlocked after the request")
+	}
+
+	// Locked by another request
+	provider.locked = true
+
+	handler(ctx)
+
+	if loadErr != ErrSessionLocked {
+		t.Errorf("MiddlewareLoadErrorHandler() error == %v, want %v", loadErr, ErrSessionLocked)
+	}
+}
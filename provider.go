@@ -0,0 +1,183 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// requestCtxKey is the key of the provider context value
+// where the current request is kept
+type requestCtxKey struct{}
+
+// RequestCtxFromContext returns the request which the provider call belongs to,
+// or nil if the call is not made on behalf of a request
+//
+// It allows the providers to read and write the request and response,
+// but it must not be used once the provider call returns
+func RequestCtxFromContext(ctx context.Context) *fasthttp.RequestCtx {
+	reqCtx, _ := ctx.Value(requestCtxKey{}).(*fasthttp.RequestCtx)
+
+	return reqCtx
+}
+
+// keepAliveKey is the key of the provider context value
+// which marks the sessions kept alive while the client is open
+type keepAliveKey struct{}
+
+// KeepAliveFromContext returns whether the provider call belongs to a session
+// which expires when the client is closed (expiration -1)
+//
+// The expiration received by the provider is a fixed keep alive one,
+// so the providers which write to the client (e.g. cookies) use it to set the expiration there
+func KeepAliveFromContext(ctx context.Context) bool {
+	keepAlive, _ := ctx.Value(keepAliveKey{}).(bool)
+
+	return keepAlive
+}
+
+// withKeepAlive returns a copy of the given context marked as a kept alive session,
+// if the given expiration is -1
+func withKeepAlive(ctx context.Context, expiration time.Duration) context.Context {
+	if expiration != -1 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, keepAliveKey{}, true)
+}
+
+// newSessionProvider returns the given provider with its optional capabilities
+func newSessionProvider(provider Provider) *sessionProvider {
+	p := &sessionProvider{
+		provider:    provider,
+		providerCtx: toProviderContext(provider),
+		toucher:     toToucherContext(provider),
+		stopGCChan:  make(chan struct{}),
+		gcDone:      make(chan struct{}),
+	}
+
+	p.gcCtx, p.gcCancel = context.WithCancel(context.Background())
+
+	p.cas, _ = provider.(CompareAndSaver)
+	p.locker, _ = provider.(Locker)
+	p.indexer, _ = provider.(UserIndexer)
+	p.scanner, _ = provider.(Scanner)
+	p.checker, _ = provider.(HealthChecker)
+
+	return p
+}
+
+// stopGC signals the GC of the provider to stop and cancels the in-flight GC,
+// it could be called many times
+func (p *sessionProvider) stopGC() {
+	p.stopGCOnce.Do(func() {
+		close(p.stopGCChan)
+		p.gcCancel()
+	})
+}
+
+// providerAdapter wraps a Provider that is not context-aware,
+// so it could be used as a ProviderContext
+type providerAdapter struct {
+	provider Provider
+}
+
+func newProviderAdapter(provider Provider) *providerAdapter {
+	return &providerAdapter{provider: provider}
+}
+
+// toucherAdapter wraps a Toucher that is not context-aware,
+// so it could be used as a ToucherContext
+type toucherAdapter struct {
+	toucher Toucher
+}
+
+func toProviderContext(provider Provider) ProviderContext {
+	if p, ok := provider.(ProviderContext); ok {
+		return p
+	}
+
+	return newProviderAdapter(provider)
+}
+
+// toToucherContext returns nil if the provider could not refresh the expiration
+func toToucherContext(provider Provider) ToucherContext {
+	switch p := provider.(type) {
+	case ToucherContext:
+		return p
+	case Toucher:
+		return &toucherAdapter{toucher: p}
+	}
+
+	return nil
+}
+
+// GetContext returns the data of the given session id
+func (a *providerAdapter) GetContext(ctx context.Context, id []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return a.provider.Get(id)
+}
+
+// SaveContext saves the session data and expiration from the given session id
+func (a *providerAdapter) SaveContext(ctx context.Context, id, data []byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return a.provider.Save(id, data, expiration)
+}
+
+// DestroyContext destroys the session from the given id
+func (a *providerAdapter) DestroyContext(ctx context.Context, id []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return a.provider.Destroy(id)
+}
+
+// RegenerateContext updates the session id and expiration with the new session id
+// of the the given current session id
+func (a *providerAdapter) RegenerateContext(ctx context.Context, id, newID []byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return a.provider.Regenerate(id, newID, expiration)
+}
+
+// CountContext returns the total of stored sessions
+func (a *providerAdapter) CountContext(ctx context.Context) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+
+	return a.provider.Count()
+}
+
+// NeedGC indicates if the GC needs to be run
+func (a *providerAdapter) NeedGC() bool {
+	return a.provider.NeedGC()
+}
+
+// GCContext destroys the expired sessions
+func (a *providerAdapter) GCContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return a.provider.GC()
+}
+
+// TouchContext refreshes the expiration of the given session id
+func (a *toucherAdapter) TouchContext(ctx context.Context, id []byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return a.toucher.Touch(id, expiration)
+}
@@ -0,0 +1,293 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type mockProviderContext struct {
+	mockProvider
+
+	deadline    time.Time
+	hasDeadline bool
+}
+
+func (p *mockProviderContext) GetContext(ctx context.Context, id []byte) ([]byte, error) {
+	p.deadline, p.hasDeadline = ctx.Deadline()
+
+	return nil, ctx.Err()
+}
+
+func (p *mockProviderContext) SaveContext(ctx context.Context, id, data []byte, expiration time.Duration) error {
+	p.deadline, p.hasDeadline = ctx.Deadline()
+
+	return ctx.Err()
+}
+
+func (p *mockProviderContext) DestroyContext(ctx context.Context, id []byte) error {
+	p.deadline, p.hasDeadline = ctx.Deadline()
+
+	return ctx.Err()
+}
+
+func (p *mockProviderContext) RegenerateContext(ctx context.Context, id, newID []byte, expiration time.Duration) error {
+	p.deadline, p.hasDeadline = ctx.Deadline()
+
+	return ctx.Err()
+}
+
+func (p *mockProviderContext) CountContext(ctx context.Context) int {
+	return p.countValue
+}
+
+func (p *mockProviderContext) GCContext(ctx context.Context) error {
+	p.gcExecuted = true
+
+	return p.errGC
+}
+
+type mockToucher struct {
+	mockProvider
+
+	touchExecuted bool
+	saveExecuted  bool
+	errTouch      error
+}
+
+func (p *mockToucher) Save(id, data []byte, expiration time.Duration) error {
+	p.saveExecuted = true
+
+	return p.errSave
+}
+
+func (p *mockToucher) Touch(id []byte, expiration time.Duration) error {
+	p.touchExecuted = true
+
+	return p.errTouch
+}
+
+type mockCompareAndSaver struct {
+	mockProvider
+
+	data    []byte
+	version uint64
+}
+
+func (p *mockCompareAndSaver) GetVersion(ctx context.Context, id []byte) ([]byte, uint64, error) {
+	if p.version == 0 {
+		return nil, 0, ErrSessionNotFound
+	}
+
+	return p.data, p.version, nil
+}
+
+func (p *mockCompareAndSaver) CompareAndSave(ctx context.Context, id, data []byte, version uint64, expiration time.Duration) (uint64, error) {
+	if version != p.version {
+		return 0, ErrConcurrentModification
+	}
+
+	p.data = data
+	p.version++
+
+	return p.version, nil
+}
+
+type mockLocker struct {
+	mockProvider
+
+	token  uint64
+	locked bool
+	calls  int
+}
+
+func (p *mockLocker) Lock(ctx context.Context, id []byte, ttl time.Duration) (uint64, error) {
+	p.calls++
+
+	if p.locked {
+		return 0, ErrSessionLocked
+	}
+
+	p.locked = true
+	p.token++
+
+	return p.token, nil
+}
+
+func (p *mockLocker) Unlock(ctx context.Context, id []byte, token uint64) error {
+	if token == p.token {
+		p.locked = false
+	}
+
+	return nil
+}
+
+type mockUserIndexer struct {
+	mockProvider
+
+	users        map[string][][]byte
+	destroyed    [][]byte
+	destroyDelay time.Duration
+}
+
+func (p *mockUserIndexer) Destroy(id []byte) error {
+	time.Sleep(p.destroyDelay)
+
+	p.destroyed = append(p.destroyed, id)
+
+	return nil
+}
+
+func (p *mockUserIndexer) BindUser(ctx context.Context, id, userID []byte) error {
+	if p.users == nil {
+		p.users = make(map[string][][]byte)
+	}
+
+	p.users[string(userID)] = append(p.users[string(userID)], append([]byte(nil), id...))
+
+	return nil
+}
+
+func (p *mockUserIndexer) UserSessions(ctx context.Context, userID []byte) ([][]byte, error) {
+	return p.users[string(userID)], nil
+}
+
+type mockScanner struct {
+	mockProvider
+
+	sessions []SessionInfo
+}
+
+func (p *mockScanner) Scan(ctx context.Context, cursor string, limit int) ([]SessionInfo, string, error) {
+	start := 0
+	if cursor != "" {
+		start, _ = strconv.Atoi(cursor)
+	}
+
+	end := start + limit
+	if end >= len(p.sessions) {
+		return p.sessions[start:], "", nil
+	}
+
+	return p.sessions[start:end], strconv.Itoa(end), nil
+}
+
+func Test_toProviderContext(t *testing.T) {
+	provider := new(mockProvider)
+
+	if _, ok := toProviderContext(provider).(*providerAdapter); !ok {
+		t.Error("toProviderContext() must wrap a non context-aware provider with an adapter")
+	}
+
+	providerCtx := new(mockProviderContext)
+
+	if v := toProviderContext(providerCtx); v != providerCtx {
+		t.Errorf("toProviderContext() == %p, want %p", v, providerCtx)
+	}
+}
+
+func TestProviderAdapter(t *testing.T) {
+	provider := &mockProvider{
+		errGet:        errors.New("get"),
+		errSave:       errors.New("save"),
+		errDestroy:    errors.New("destroy"),
+		errRegenerate: errors.New("regenerate"),
+		errGC:         errors.New("gc"),
+		countValue:    3,
+		needGCValue:   true,
+	}
+	adapter := newProviderAdapter(provider)
+	ctx := context.Background()
+
+	if _, err := adapter.GetContext(ctx, nil); err != provider.errGet {
+		t.Errorf("providerAdapter.GetContext() error == %v, want %v", err, provider.errGet)
+	}
+
+	if err := adapter.SaveContext(ctx, nil, nil, 0); err != provider.errSave {
+		t.Errorf("providerAdapter.SaveContext() error == %v, want %v", err, provider.errSave)
+	}
+
+	if err := adapter.DestroyContext(ctx, nil); err != provider.errDestroy {
+		t.Errorf("providerAdapter.DestroyContext() error == %v, want %v", err, provider.errDestroy)
+	}
+
+	if err := adapter.RegenerateContext(ctx, nil, nil, 0); err != provider.errRegenerate {
+		t.Errorf("providerAdapter.RegenerateContext() error == %v, want %v", err, provider.errRegenerate)
+	}
+
+	if v := adapter.CountContext(ctx); v != provider.countValue {
+		t.Errorf("providerAdapter.CountContext() == %d, want %d", v, provider.countValue)
+	}
+
+	if v := adapter.NeedGC(); v != provider.needGCValue {
+		t.Errorf("providerAdapter.NeedGC() == %v, want %v", v, provider.needGCValue)
+	}
+
+	if err := adapter.GCContext(ctx); err != provider.errGC {
+		t.Errorf("providerAdapter.GCContext() error == %v, want %v", err, provider.errGC)
+	}
+}
+
+func TestProviderAdapter_Canceled(t *testing.T) {
+	provider := new(mockProvider)
+	adapter := newProviderAdapter(provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := adapter.GetContext(ctx, nil); err != context.Canceled {
+		t.Errorf("providerAdapter.GetContext() error == %v, want %v", err, context.Canceled)
+	}
+
+	if err := adapter.SaveContext(ctx, nil, nil, 0); err != context.Canceled {
+		t.Errorf("providerAdapter.SaveContext() error == %v, want %v", err, context.Canceled)
+	}
+
+	if err := adapter.DestroyContext(ctx, nil); err != context.Canceled {
+		t.Errorf("providerAdapter.DestroyContext() error == %v, want %v", err, context.Canceled)
+	}
+
+	if err := adapter.RegenerateContext(ctx, nil, nil, 0); err != context.Canceled {
+		t.Errorf("providerAdapter.RegenerateContext() error == %v, want %v", err, context.Canceled)
+	}
+
+	if err := adapter.GCContext(ctx); err != context.Canceled {
+		t.Errorf("providerAdapter.GCContext() error == %v, want %v", err, context.Canceled)
+	}
+
+	if provider.gcExecuted {
+		t.Error("GC is executed with a canceled context")
+	}
+}
+
+func Test_toToucherContext(t *testing.T) {
+	if v := toToucherContext(new(mockProvider)); v != nil {
+		t.Errorf("toToucherContext() == %v, want %v", v, nil)
+	}
+
+	provider := &mockToucher{errTouch: errors.New("touch")}
+
+	toucher := toToucherContext(provider)
+	if _, ok := toucher.(*toucherAdapter); !ok {
+		t.Fatal("toToucherContext() must wrap a non context-aware toucher with an adapter")
+	}
+
+	if err := toucher.TouchContext(context.Background(), nil, 0); err != provider.errTouch {
+		t.Errorf("toucherAdapter.TouchContext() error == %v, want %v", err, provider.errTouch)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	provider.touchExecuted = false
+
+	if err := toucher.TouchContext(ctx, nil, 0); err != context.Canceled {
+		t.Errorf("toucherAdapter.TouchContext() error == %v, want %v", err, context.Canceled)
+	}
+
+	if provider.touchExecuted {
+		t.Error("Touch is executed with a canceled context")
+	}
+}
@@ -0,0 +1,9 @@
+package cookie
+
+import "errors"
+
+var (
+	ErrConfigCookieNameEmpty = errors.New("Config CookieName must not be empty")
+	ErrConfigKeysEmpty       = errors.New("Config Keys must not be empty")
+	ErrNotRequestContext     = errors.New("The provider context does not belong to a request")
+)
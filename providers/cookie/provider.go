@@ -0,0 +1,361 @@
+package cookie
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"strconv"
+	"time"
+
+	"github.com/fasthttp/session/v2"
+	"github.com/valyala/fasthttp"
+)
+
+const defaultMaxCookieValueSize = 3800
+
+const defaultCookiePath = "/"
+
+// Size of the expiration timestamp embedded in the encrypted data
+const timestampSize = 8
+
+var encoding = base64.RawURLEncoding
+
+// New returns a new configured cookie provider
+func New(cfg Config) (*Provider, error) {
+	if cfg.CookieName == "" {
+		return nil, ErrConfigCookieNameEmpty
+	}
+	if len(cfg.Keys) == 0 {
+		return nil, ErrConfigKeysEmpty
+	}
+
+	if cfg.MaxCookieValueSize <= 0 {
+		cfg.MaxCookieValueSize = defaultMaxCookieValueSize
+	}
+
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = defaultCookiePath
+	}
+
+	if err := session.ValidateCookie(cfg.CookieName, cfg.CookiePath, cfg.Domain, cfg.Secure); err != nil {
+		return nil, err
+	}
+
+	aeads := make([]cipher.AEAD, len(cfg.Keys))
+
+	for i, key := range cfg.Keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		aeads[i] = aead
+	}
+
+	p := &Provider{
+		config: cfg,
+		aeads:  aeads,
+	}
+
+	return p, nil
+}
+
+func (p *Provider) getCookieName(i int) string {
+	if i == 0 {
+		return p.config.CookieName
+	}
+
+	return p.config.CookieName + "." + strconv.Itoa(i)
+}
+
+// countCookies returns the number of cookies of the session data sent by the client
+func (p *Provider) countCookies(ctx *fasthttp.RequestCtx) int {
+	n := 0
+
+	for len(ctx.Request.Header.Cookie(p.getCookieName(n))) > 0 {
+		n++
+	}
+
+	return n
+}
+
+func (p *Provider) readCookies(ctx *fasthttp.RequestCtx) []byte {
+	var value []byte
+
+	for i, n := 0, p.countCookies(ctx); i < n; i++ {
+		value = append(value, ctx.Request.Header.Cookie(p.getCookieName(i))...)
+	}
+
+	return value
+}
+
+// setCookieAttrs sets the configured attributes of the cookie
+func (p *Provider) setCookieAttrs(cookie *fasthttp.Cookie) {
+	cookie.SetPath(p.config.CookiePath)
+	cookie.SetHTTPOnly(!p.config.CookieDisableHTTPOnly)
+	cookie.SetDomain(p.config.Domain)
+	cookie.SetSameSite(p.config.CookieSameSite)
+	cookie.SetSecure(p.config.Secure)
+}
+
+// setCookie sets the cookie with the given expiration,
+// or without it if the session is kept alive while the client is open
+func (p *Provider) setCookie(ctx *fasthttp.RequestCtx, name string, value []byte, expiration time.Duration, keepAlive bool) {
+	cookie := fasthttp.AcquireCookie()
+
+	cookie.SetKey(name)
+	cookie.SetValueBytes(value)
+	p.setCookieAttrs(cookie)
+
+	switch {
+	case keepAlive:
+	case expiration == 0:
+		cookie.SetExpire(fasthttp.CookieExpireUnlimited)
+	default:
+		cookie.SetExpire(time.Now().Add(expiration))
+	}
+
+	ctx.Request.Header.SetCookieBytesKV(cookie.Key(), cookie.Value())
+	ctx.Response.Header.SetCookie(cookie)
+
+	fasthttp.ReleaseCookie(cookie)
+}
+
+func (p *Provider) deleteCookie(ctx *fasthttp.RequestCtx, name string) {
+	ctx.Request.Header.DelCookie(name)
+	ctx.Response.Header.DelCookie(name)
+
+	cookie := fasthttp.AcquireCookie()
+	cookie.SetKey(name)
+	p.setCookieAttrs(cookie)
+	cookie.SetExpire(fasthttp.CookieExpireDelete)
+	ctx.Response.Header.SetCookie(cookie)
+
+	fasthttp.ReleaseCookie(cookie)
+}
+
+// writeCookies splits the value across the needed cookies,
+// and deletes the cookies of the previous value which are not needed anymore
+func (p *Provider) writeCookies(ctx *fasthttp.RequestCtx, value []byte, expiration time.Duration, keepAlive bool) {
+	prevCount := p.countCookies(ctx)
+	count := 0
+
+	for len(value) > 0 {
+		size := p.config.MaxCookieValueSize
+		if size > len(value) {
+			size = len(value)
+		}
+
+		p.setCookie(ctx, p.getCookieName(count), value[:size], expiration, keepAlive)
+
+		value = value[size:]
+		count++
+	}
+
+	for i := count; i < prevCount; i++ {
+		p.deleteCookie(ctx, p.getCookieName(i))
+	}
+}
+
+// encrypt returns the encrypted data with the first key, embedding the expiration time.
+// The session id is authenticated too, so the data could not be moved to another session.
+func (p *Provider) encrypt(id, data []byte, expiration time.Duration) ([]byte, error) {
+	aead := p.aeads[0]
+
+	var expiresAt int64
+	if expiration > 0 {
+		expiresAt = time.Now().Add(expiration).UnixNano()
+	}
+
+	plaintext := make([]byte, timestampSize+len(data))
+	binary.BigEndian.PutUint64(plaintext, uint64(expiresAt))
+	copy(plaintext[timestampSize:], data)
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	ciphertext := aead.Seal(nonce, nonce, plaintext, id)
+
+	value := make([]byte, encoding.EncodedLen(len(ciphertext)))
+	encoding.Encode(value, ciphertext)
+
+	return value, nil
+}
+
+// decrypt returns the decrypted data with any of the keys
+//
+// Returns session.ErrSessionNotFound if the value is not valid or it's expired
+func (p *Provider) decrypt(id, value []byte) ([]byte, error) {
+	ciphertext := make([]byte, encoding.DecodedLen(len(value)))
+
+	n, err := encoding.Decode(ciphertext, value)
+	if err != nil {
+		return nil, session.ErrSessionNotFound
+	}
+
+	ciphertext = ciphertext[:n]
+
+	for _, aead := range p.aeads {
+		nonceSize := aead.NonceSize()
+		if len(ciphertext) < nonceSize {
+			break
+		}
+
+		plaintext, err := aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], id)
+		if err != nil || len(plaintext) < timestampSize {
+			continue
+		}
+
+		expiresAt := int64(binary.BigEndian.Uint64(plaintext))
+		if expiresAt != 0 && time.Now().UnixNano() >= expiresAt {
+			return nil, session.ErrSessionNotFound
+		}
+
+		return plaintext[timestampSize:], nil
+	}
+
+	return nil, session.ErrSessionNotFound
+}
+
+// Get returns the data of the given session id
+//
+// The cookie provider needs the request, so use GetContext
+func (p *Provider) Get(id []byte) ([]byte, error) {
+	return p.GetContext(context.Background(), id)
+}
+
+// GetContext returns the decrypted data of the given session id from the request cookies
+//
+// Returns session.ErrSessionNotFound if the session data does not exist,
+// it's not valid or it's expired
+func (p *Provider) GetContext(ctx context.Context, id []byte) ([]byte, error) {
+	reqCtx := session.RequestCtxFromContext(ctx)
+	if reqCtx == nil {
+		return nil, ErrNotRequestContext
+	}
+
+	value := p.readCookies(reqCtx)
+	if len(value) == 0 {
+		return nil, session.ErrSessionNotFound
+	}
+
+	return p.decrypt(id, value)
+}
+
+// Save saves the session data and expiration from the given session id
+//
+// The cookie provider needs the request, so use SaveContext
+func (p *Provider) Save(id, data []byte, expiration time.Duration) error {
+	return p.SaveContext(context.Background(), id, data, expiration)
+}
+
+// SaveContext encrypts the session data and expiration from the given session id
+// and sets it in the response cookies
+//
+// The cookies of the sessions kept alive while the client is open are set without expiration
+func (p *Provider) SaveContext(ctx context.Context, id, data []byte, expiration time.Duration) error {
+	reqCtx := session.RequestCtxFromContext(ctx)
+	if reqCtx == nil {
+		return ErrNotRequestContext
+	}
+
+	value, err := p.encrypt(id, data, expiration)
+	if err != nil {
+		return err
+	}
+
+	p.writeCookies(reqCtx, value, expiration, session.KeepAliveFromContext(ctx))
+
+	return nil
+}
+
+// Regenerate updates the session id and expiration with the new session id
+// of the the given current session id
+//
+// The cookie provider needs the request, so use RegenerateContext
+func (p *Provider) Regenerate(id, newID []byte, expiration time.Duration) error {
+	return p.RegenerateContext(context.Background(), id, newID, expiration)
+}
+
+// RegenerateContext re-encrypts the session data with the new session id and expiration
+// of the the given current session id
+func (p *Provider) RegenerateContext(ctx context.Context, id, newID []byte, expiration time.Duration) error {
+	data, err := p.GetContext(ctx, id)
+	if err == session.ErrSessionNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return p.SaveContext(ctx, newID, data, expiration)
+}
+
+// Destroy destroys the session from the given id
+//
+// The cookie provider needs the request, so use DestroyContext
+func (p *Provider) Destroy(id []byte) error {
+	return p.DestroyContext(context.Background(), id)
+}
+
+// DestroyContext deletes the session data cookies
+func (p *Provider) DestroyContext(ctx context.Context, id []byte) error {
+	reqCtx := session.RequestCtxFromContext(ctx)
+	if reqCtx == nil {
+		return ErrNotRequestContext
+	}
+
+	for i, n := 0, p.countCookies(reqCtx); i < n; i++ {
+		p.deleteCookie(reqCtx, p.getCookieName(i))
+	}
+
+	return nil
+}
+
+// Count returns the total of stored sessions
+//
+// The sessions are stored by the clients, so they could not be counted
+func (p *Provider) Count() int {
+	return p.CountContext(context.Background())
+}
+
+// CountContext returns the total of stored sessions
+//
+// The sessions are stored by the clients, so they could not be counted
+func (p *Provider) CountContext(ctx context.Context) int {
+	return 0
+}
+
+// NeedGC indicates if the GC needs to be run
+func (p *Provider) NeedGC() bool {
+	return false
+}
+
+// GC destroys the expired sessions
+func (p *Provider) GC() error {
+	return p.GCContext(context.Background())
+}
+
+// GCContext destroys the expired sessions
+//
+// The expiration is enforced when the session data is decrypted
+func (p *Provider) GCContext(ctx context.Context) error {
+	return nil
+}
+
+// HealthCheck returns nil, since the sessions are stored by the clients
+//
+// The sessions could only be read from the cookies of a request,
+// so the health could not be checked by reading an unknown session id
+func (p *Provider) HealthCheck(ctx context.Context) error {
+	return nil
+}
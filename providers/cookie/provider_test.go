@@ -0,0 +1,455 @@
+package cookie
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/session/v2"
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	testKey1 = []byte("0123456789abcdef0123456789abcdef")
+	testKey2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func newTestProvider(t *testing.T, keys ...[]byte) *Provider {
+	t.Helper()
+
+	if len(keys) == 0 {
+		keys = [][]byte{testKey1}
+	}
+
+	p, err := New(Config{
+		CookieName: "sessiondata",
+		Keys:       keys,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return p
+}
+
+func newTestSession(t *testing.T, provider *Provider) *session.Session {
+	t.Helper()
+
+	s := session.New(session.NewDefaultConfig())
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return s
+}
+
+// nextRequest returns a new request with the cookies of the given response
+func nextRequest(ctx *fasthttp.RequestCtx) *fasthttp.RequestCtx {
+	next := new(fasthttp.RequestCtx)
+
+	ctx.Response.Header.VisitAllCookie(func(key, value []byte) {
+		cookie := fasthttp.AcquireCookie()
+		defer fasthttp.ReleaseCookie(cookie)
+
+		if err := cookie.ParseBytes(value); err != nil {
+			return
+		}
+
+		if cookie.Expire().Before(time.Now()) {
+			return
+		}
+
+		next.Request.Header.SetCookieBytesKV(cookie.Key(), cookie.Value())
+	})
+
+	return next
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Config{Keys: [][]byte{testKey1}}); err != ErrConfigCookieNameEmpty {
+		t.Errorf("Expected error: %v", ErrConfigCookieNameEmpty)
+	}
+
+	if _, err := New(Config{CookieName: "sessiondata"}); err != ErrConfigKeysEmpty {
+		t.Errorf("Expected error: %v", ErrConfigKeysEmpty)
+	}
+
+	if _, err := New(Config{CookieName: "sessiondata", Keys: [][]byte{[]byte("short")}}); err == nil {
+		t.Error("Expected error with an invalid key size")
+	}
+
+	if _, err := New(Config{CookieName: "__Host-data", Keys: [][]byte{testKey1}, Secure: true, CookiePath: "/app"}); err != session.ErrInvalidHostCookie {
+		t.Errorf("Expected error: %v", session.ErrInvalidHostCookie)
+	}
+
+	if _, err := New(Config{CookieName: "__Secure-data", Keys: [][]byte{testKey1}}); err != session.ErrInvalidSecureCookie {
+		t.Errorf("Expected error: %v", session.ErrInvalidSecureCookie)
+	}
+
+	if _, err := New(Config{CookieName: "__Host-data", Keys: [][]byte{testKey1}, Secure: true}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	p := newTestProvider(t)
+	if p.config.MaxCookieValueSize != defaultMaxCookieValueSize {
+		t.Errorf("Provider.config.MaxCookieValueSize == %d, want %d", p.config.MaxCookieValueSize, defaultMaxCookieValueSize)
+	}
+
+	if p.config.CookiePath != defaultCookiePath {
+		t.Errorf("Provider.config.CookiePath == %s, want %s", p.config.CookiePath, defaultCookiePath)
+	}
+}
+
+func TestProvider_CookieAttributes(t *testing.T) {
+	p, err := New(Config{
+		CookieName:            "sessiondata",
+		Keys:                  [][]byte{testKey1},
+		Domain:                "example.com",
+		CookiePath:            "/app",
+		CookieDisableHTTPOnly: true,
+		Secure:                true,
+		CookieSameSite:        fasthttp.CookieSameSiteStrictMode,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expiration := range []time.Duration{time.Hour, -1} {
+		cfg := session.NewDefaultConfig()
+		cfg.Expiration = expiration
+
+		s := session.New(cfg)
+
+		if err := s.SetProvider(p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		ctx := new(fasthttp.RequestCtx)
+
+		store, err := s.Get(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		store.Set("foo", "bar")
+
+		if err := s.Save(ctx, store); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		cookie := fasthttp.AcquireCookie()
+		cookie.SetKey(p.config.CookieName)
+
+		if !ctx.Response.Header.Cookie(cookie) {
+			t.Fatalf("The session data cookie is not set")
+		}
+
+		if v := string(cookie.Domain()); v != "example.com" {
+			t.Errorf("Cookie.Domain() == %s, want %s", v, "example.com")
+		}
+
+		if v := string(cookie.Path()); v != "/app" {
+			t.Errorf("Cookie.Path() == %s, want %s", v, "/app")
+		}
+
+		if cookie.HTTPOnly() {
+			t.Error("Cookie.HTTPOnly() == true, want false")
+		}
+
+		if !cookie.Secure() {
+			t.Error("Cookie.Secure() == false, want true")
+		}
+
+		if v := cookie.SameSite(); v != fasthttp.CookieSameSiteStrictMode {
+			t.Errorf("Cookie.SameSite() == %v, want %v", v, fasthttp.CookieSameSiteStrictMode)
+		}
+
+		// The kept alive sessions expire when the client is closed
+		if hasExpire := cookie.Expire() != fasthttp.CookieExpireUnlimited; hasExpire != (expiration != -1) {
+			t.Errorf("Expiration %v: Cookie.Expire() == %v", expiration, cookie.Expire())
+		}
+
+		fasthttp.ReleaseCookie(cookie)
+	}
+}
+
+func TestProvider_NotRequestContext(t *testing.T) {
+	p := newTestProvider(t)
+
+	if _, err := p.Get([]byte("id")); err != ErrNotRequestContext {
+		t.Errorf("Expected error: %v", ErrNotRequestContext)
+	}
+
+	if err := p.Save([]byte("id"), []byte("data"), 0); err != ErrNotRequestContext {
+		t.Errorf("Expected error: %v", ErrNotRequestContext)
+	}
+
+	if err := p.Destroy([]byte("id")); err != ErrNotRequestContext {
+		t.Errorf("Expected error: %v", ErrNotRequestContext)
+	}
+}
+
+func TestProvider_encryptDecrypt(t *testing.T) {
+	p := newTestProvider(t)
+
+	id := []byte("id")
+	data := []byte("data")
+
+	value, err := p.encrypt(id, data, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if bytes.Contains(value, data) {
+		t.Error("The data is not encrypted")
+	}
+
+	result, err := p.decrypt(id, value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(result, data) {
+		t.Errorf("Provider.decrypt() == %s, want %s", result, data)
+	}
+
+	// Other session id
+	if _, err := p.decrypt([]byte("other"), value); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Provider.decrypt() with other session id error == %v, want %v", err, session.ErrSessionNotFound)
+	}
+
+	// Tampered value
+	tampered := append([]byte{}, value...)
+	tampered[len(tampered)-2] ^= 1
+
+	if _, err := p.decrypt(id, tampered); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Provider.decrypt() with tampered value error == %v, want %v", err, session.ErrSessionNotFound)
+	}
+
+	// Expired value
+	value, err = p.encrypt(id, data, time.Nanosecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	if _, err := p.decrypt(id, value); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Provider.decrypt() with expired value error == %v, want %v", err, session.ErrSessionNotFound)
+	}
+}
+
+func TestProvider_KeyRotation(t *testing.T) {
+	id := []byte("id")
+	data := []byte("data")
+
+	oldProvider := newTestProvider(t, testKey1)
+
+	value, err := oldProvider.encrypt(id, data, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := newTestProvider(t, testKey2, testKey1)
+
+	result, err := p.decrypt(id, value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(result, data) {
+		t.Errorf("Provider.decrypt() with a rotated key == %s, want %s", result, data)
+	}
+
+	if _, err := newTestProvider(t, testKey2).decrypt(id, value); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Errorf("Provider.decrypt() with a removed key error == %v, want %v", err, session.ErrSessionNotFound)
+	}
+}
+
+func TestProvider_Session(t *testing.T) {
+	p := newTestProvider(t)
+	s := newTestSession(t, p)
+
+	ctx := new(fasthttp.RequestCtx)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := string(store.GetSessionID())
+	store.Set("foo", "bar")
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx = nextRequest(ctx)
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := string(store.GetSessionID()); v != id {
+		t.Errorf("Store.GetSessionID() == %s, want %s", v, id)
+	}
+
+	if v := store.Get("foo"); v != "bar" {
+		t.Errorf("Store.Get() == %v, want %v", v, "bar")
+	}
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := s.Destroy(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx = nextRequest(ctx)
+
+	if v := ctx.Request.Header.Cookie(p.config.CookieName); len(v) > 0 {
+		t.Errorf("The session data cookie is not deleted: %s", v)
+	}
+}
+
+func TestProvider_SessionChunks(t *testing.T) {
+	p := newTestProvider(t)
+	p.config.MaxCookieValueSize = 256
+
+	s := newTestSession(t, p)
+
+	ctx := new(fasthttp.RequestCtx)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value := strings.Repeat("x", 1000)
+	store.Set("big", value)
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx = nextRequest(ctx)
+
+	count := p.countCookies(ctx)
+	if count < 2 {
+		t.Fatalf("The session data is not split, cookies == %d", count)
+	}
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := store.Get("big"); v != value {
+		t.Errorf("Store.Get() == %v, want %v", v, value)
+	}
+
+	// The cookies which are not needed anymore are deleted
+	store.Delete("big")
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx = nextRequest(ctx)
+
+	if v := p.countCookies(ctx); v != 1 {
+		t.Errorf("Session data cookies == %d, want %d", v, 1)
+	}
+}
+
+func TestProvider_Regenerate(t *testing.T) {
+	p := newTestProvider(t)
+	s := newTestSession(t, p)
+
+	ctx := new(fasthttp.RequestCtx)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := string(store.GetSessionID())
+	store.Set("foo", "bar")
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx = nextRequest(ctx)
+
+	if err := s.Regenerate(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx = nextRequest(ctx)
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := string(store.GetSessionID()); v == id {
+		t.Error("The session id is not regenerated")
+	}
+
+	if v := store.Get("foo"); v != "bar" {
+		t.Errorf("Store.Get() == %v, want %v", v, "bar")
+	}
+}
+
+func TestProvider_StrictSessionID(t *testing.T) {
+	cfg := session.NewDefaultConfig()
+	cfg.StrictSessionID = true
+
+	s := session.New(cfg)
+
+	if err := s.SetProvider(newTestProvider(t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attackerID := "attacker-id"
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(cfg.CookieName, attackerID)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := string(store.GetSessionID()); v == attackerID {
+		t.Error("Session.Get() adopts an unknown session id in strict mode")
+	}
+}
+
+func TestProvider_AdminStats(t *testing.T) {
+	s := session.New(session.Config{})
+
+	if err := s.SetProvider(newTestProvider(t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	handler := session.AdminHandler(s, session.AdminOptions{
+		Authorize: func(ctx *fasthttp.RequestCtx) bool { return true },
+	})
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+	ctx.Request.SetRequestURI("/stats")
+
+	handler(ctx)
+
+	if v := ctx.Response.StatusCode(); v != fasthttp.StatusOK {
+		t.Errorf("Status code of the stats == %d, want %d: %s", v, fasthttp.StatusOK, ctx.Response.Body())
+	}
+}
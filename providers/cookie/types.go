@@ -0,0 +1,51 @@
+package cookie
+
+import (
+	"crypto/cipher"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Config provider settings
+type Config struct {
+	// Name of the cookie which stores the session data.
+	// If the data does not fit in a single cookie, it's split across multiple
+	// numbered cookies: name, name.1, name.2, ...
+	//
+	// Must be different of the session id cookie name.
+	// The names with the __Secure- prefix require Secure,
+	// and the names with the __Host- prefix require Secure, the "/" CookiePath and no Domain.
+	CookieName string
+
+	// Cookie domain
+	Domain string
+
+	// CookiePath is the path of the cookie, "/" by default.
+	CookiePath string
+
+	// CookieDisableHTTPOnly removes the HttpOnly attribute of the cookie,
+	// so it could be read by the client scripts.
+	CookieDisableHTTPOnly bool
+
+	// Set whether to pass the cookie only through HTTPS
+	Secure bool
+
+	// Allows you to declare if your cookie should be restricted to a first-party or same-site context.
+	CookieSameSite fasthttp.CookieSameSite
+
+	// AES keys (16, 24 or 32 bytes) used to encrypt and authenticate the session data with AES-GCM.
+	//
+	// The first key is used to encrypt, and all of them are used to decrypt,
+	// so the keys could be rotated by adding the new one at the beginning.
+	Keys [][]byte
+
+	// Maximum size of the value of each cookie.
+	// Default is 3800 bytes, so the cookie with its attributes fits in the browsers limit (4KB).
+	MaxCookieValueSize int
+}
+
+// Provider backend manager
+type Provider struct {
+	config Config
+	aeads  []cipher.AEAD
+}
@@ -1,11 +1,14 @@
 package memcache
 
 import (
+	"context"
+	"hash/fnv"
 	"math"
 	"sync"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/fasthttp/session/v2"
 	"github.com/valyala/bytebufferpool"
 )
 
@@ -69,11 +72,24 @@ func (p *Provider) getMemCacheSessionKey(sessionID []byte) string {
 
 // Get returns the data of the given session id
 func (p *Provider) Get(id []byte) ([]byte, error) {
+	return p.GetContext(context.Background(), id)
+}
+
+// GetContext returns the data of the given session id
+//
+// Returns session.ErrSessionNotFound if the session does not exist.
+// The memcache client does not support contexts,
+// so the context is only checked before the call
+func (p *Provider) GetContext(ctx context.Context, id []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	key := p.getMemCacheSessionKey(id)
 
 	item, err := p.db.Get(key)
 	if err == memcache.ErrCacheMiss {
-		return nil, nil
+		return nil, session.ErrSessionNotFound
 	} else if err != nil {
 		return nil, err
 	}
@@ -83,6 +99,15 @@ func (p *Provider) Get(id []byte) ([]byte, error) {
 
 // Save saves the session data and expiration from the given session id
 func (p *Provider) Save(id, data []byte, expiration time.Duration) error {
+	return p.SaveContext(context.Background(), id, data, expiration)
+}
+
+// SaveContext saves the session data and expiration from the given session id
+func (p *Provider) SaveContext(ctx context.Context, id, data []byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	mcExpiration := int32(expiration.Seconds())
 	if mcExpiration > math.MaxInt32 {
 		return ErrExpirationIsTooBig
@@ -100,9 +125,95 @@ func (p *Provider) Save(id, data []byte, expiration time.Duration) error {
 	return err
 }
 
+// dataVersion returns the version of the given data
+func dataVersion(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+
+	return h.Sum64()
+}
+
+// GetVersion returns the data and the version of the given session id
+//
+// The version is derived from the data, so the sessions stored before
+// don't need to be migrated.
+// Returns session.ErrSessionNotFound if the session does not exist
+func (p *Provider) GetVersion(ctx context.Context, id []byte) ([]byte, uint64, error) {
+	data, err := p.GetContext(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return data, dataVersion(data), nil
+}
+
+// CompareAndSave saves the session data and expiration from the given session id
+// only if its stored version is the given one, and returns the new version
+//
+// The save is executed with the memcache CAS command, so it fails
+// if the session is modified between the version comparison and the save.
+// Returns session.ErrConcurrentModification if the stored version is another one
+func (p *Provider) CompareAndSave(ctx context.Context, id, data []byte, version uint64, expiration time.Duration) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	mcExpiration := int32(expiration.Seconds())
+	if mcExpiration > math.MaxInt32 {
+		return 0, ErrExpirationIsTooBig
+	}
+
+	key := p.getMemCacheSessionKey(id)
+
+	item, err := p.db.Get(key)
+	if err == memcache.ErrCacheMiss { // Not exist
+		if version != 0 {
+			return 0, session.ErrConcurrentModification
+		}
+
+		newItem := acquireItem()
+		newItem.Key = key
+		newItem.Value = data
+		newItem.Expiration = mcExpiration
+
+		err = p.db.Add(newItem)
+
+		releaseItem(newItem)
+	} else if err != nil {
+		return 0, err
+	} else {
+		if version == 0 || dataVersion(item.Value) != version {
+			return 0, session.ErrConcurrentModification
+		}
+
+		item.Value = data
+		item.Expiration = mcExpiration
+
+		err = p.db.CompareAndSwap(item)
+	}
+
+	if err == memcache.ErrNotStored || err == memcache.ErrCASConflict {
+		return 0, session.ErrConcurrentModification
+	} else if err != nil {
+		return 0, err
+	}
+
+	return dataVersion(data), nil
+}
+
 // Regenerate updates the session id and expiration with the new session id
 // of the the given current session id
 func (p *Provider) Regenerate(id, newID []byte, expiration time.Duration) error {
+	return p.RegenerateContext(context.Background(), id, newID, expiration)
+}
+
+// RegenerateContext updates the session id and expiration with the new session id
+// of the the given current session id
+func (p *Provider) RegenerateContext(ctx context.Context, id, newID []byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	key := p.getMemCacheSessionKey(id)
 	newKey := p.getMemCacheSessionKey(newID)
 
@@ -136,14 +247,54 @@ func (p *Provider) Regenerate(id, newID []byte, expiration time.Duration) error
 	return nil
 }
 
+// Touch updates the expiration of the given session id
+// without rewriting its data
+func (p *Provider) Touch(id []byte, expiration time.Duration) error {
+	return p.TouchContext(context.Background(), id, expiration)
+}
+
+// TouchContext updates the expiration of the given session id
+// without rewriting its data
+func (p *Provider) TouchContext(ctx context.Context, id []byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	mcExpiration := int32(expiration.Seconds())
+	if mcExpiration > math.MaxInt32 {
+		return ErrExpirationIsTooBig
+	}
+
+	err := p.db.Touch(p.getMemCacheSessionKey(id), mcExpiration)
+	if err == memcache.ErrCacheMiss {
+		return nil
+	}
+
+	return err
+}
+
 // Destroy destroys the session from the given id
 func (p *Provider) Destroy(id []byte) error {
+	return p.DestroyContext(context.Background(), id)
+}
+
+// DestroyContext destroys the session from the given id
+func (p *Provider) DestroyContext(ctx context.Context, id []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	key := p.getMemCacheSessionKey(id)
 	return p.db.Delete(key)
 }
 
 // Count returns the total of stored sessions
 func (p *Provider) Count() int {
+	return p.CountContext(context.Background())
+}
+
+// CountContext returns the total of stored sessions
+func (p *Provider) CountContext(ctx context.Context) int {
 	return 0
 }
 
@@ -154,5 +305,15 @@ func (p *Provider) NeedGC() bool {
 
 // GC destroys the expired sessions
 func (p *Provider) GC() error {
+	return p.GCContext(context.Background())
+}
+
+// GCContext destroys the expired sessions
+func (p *Provider) GCContext(ctx context.Context) error {
 	return nil
 }
+
+// Close closes the idle connections of the memcache client
+func (p *Provider) Close() error {
+	return p.db.Close()
+}
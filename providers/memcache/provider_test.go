@@ -0,0 +1,34 @@
+package memcache
+
+import (
+	"testing"
+
+	"github.com/fasthttp/session/v2/internal/providertest"
+)
+
+func newTestProvider(t *testing.T) *Provider {
+	t.Helper()
+
+	p, err := New(Config{
+		KeyPrefix:    "providertest",
+		ServerList:   []string{providertest.Env(t, "MEMCACHE_ADDR")},
+		MaxIdleConns: 8,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return p
+}
+
+func TestProvider_GetNotFound(t *testing.T) {
+	providertest.GetNotFound(t, newTestProvider(t))
+}
+
+func TestProvider_StrictSessionID(t *testing.T) {
+	providertest.StrictSessionID(t, newTestProvider(t))
+}
+
+func TestProvider_CompareAndSave(t *testing.T) {
+	providertest.CompareAndSave(t, newTestProvider(t))
+}
@@ -1,9 +1,12 @@
 package memory
 
 import (
+	"context"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/fasthttp/session/v2"
 	"github.com/savsgio/gotils/strconv"
 )
 
@@ -21,6 +24,7 @@ func releaseItem(item *item) {
 	item.data = item.data[:0]
 	item.lastActiveTime = 0
 	item.expiration = 0
+	item.version = 0
 
 	itemPool.Put(item)
 }
@@ -29,6 +33,8 @@ func releaseItem(item *item) {
 func New(cfg Config) (*Provider, error) {
 	p := &Provider{
 		config: cfg,
+		locks:  make(map[string]lock),
+		users:  make(map[string]map[string]struct{}),
 	}
 
 	return p, nil
@@ -40,11 +46,22 @@ func (p *Provider) getSessionKey(sessionID []byte) string {
 
 // Get returns the data of the given session id
 func (p *Provider) Get(id []byte) ([]byte, error) {
+	return p.GetContext(context.Background(), id)
+}
+
+// GetContext returns the data of the given session id
+//
+// Returns session.ErrSessionNotFound if the session does not exist
+func (p *Provider) GetContext(ctx context.Context, id []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	key := p.getSessionKey(id)
 
 	val, found := p.db.Load(key)
 	if !found || val == nil { // Not exist
-		return nil, nil
+		return nil, session.ErrSessionNotFound
 	}
 
 	item := val.(*item)
@@ -54,61 +71,336 @@ func (p *Provider) Get(id []byte) ([]byte, error) {
 
 // Save saves the session data and expiration from the given session id
 func (p *Provider) Save(id, data []byte, expiration time.Duration) error {
+	return p.SaveContext(context.Background(), id, data, expiration)
+}
+
+// SaveContext saves the session data and expiration from the given session id
+func (p *Provider) SaveContext(ctx context.Context, id, data []byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	key := p.getSessionKey(id)
 
+	var version uint64
+	if val, found := p.db.Load(key); found && val != nil {
+		version = val.(*item).version
+	}
+
 	item := acquireItem()
 	item.data = data
 	item.lastActiveTime = time.Now().UnixNano()
 	item.expiration = expiration
+	item.version = version + 1
 
 	p.db.Store(key, item)
 
 	return nil
 }
 
+// GetVersion returns the data and the version of the given session id
+//
+// Returns session.ErrSessionNotFound if the session does not exist
+func (p *Provider) GetVersion(ctx context.Context, id []byte) ([]byte, uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	key := p.getSessionKey(id)
+
+	val, found := p.db.Load(key)
+	if !found || val == nil { // Not exist
+		return nil, 0, session.ErrSessionNotFound
+	}
+
+	item := val.(*item)
+
+	return item.data, item.version, nil
+}
+
+// CompareAndSave saves the session data and expiration from the given session id
+// only if its stored version is the given one, and returns the new version
+//
+// Returns session.ErrConcurrentModification if the stored version is another one
+func (p *Provider) CompareAndSave(ctx context.Context, id, data []byte, version uint64, expiration time.Duration) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	key := p.getSessionKey(id)
+
+	newItem := acquireItem()
+	newItem.data = data
+	newItem.lastActiveTime = time.Now().UnixNano()
+	newItem.expiration = expiration
+	newItem.version = version + 1
+
+	if version == 0 { // Not stored yet
+		if _, loaded := p.db.LoadOrStore(key, newItem); loaded {
+			releaseItem(newItem)
+
+			return 0, session.ErrConcurrentModification
+		}
+
+		return newItem.version, nil
+	}
+
+	val, found := p.db.Load(key)
+	if !found || val == nil || val.(*item).version != version || !p.db.CompareAndSwap(key, val, newItem) {
+		releaseItem(newItem)
+
+		return 0, session.ErrConcurrentModification
+	}
+
+	return newItem.version, nil
+}
+
 // Regenerate updates the session id and expiration with the new session id
 // of the the given current session id
 func (p *Provider) Regenerate(id, newID []byte, expiration time.Duration) error {
+	return p.RegenerateContext(context.Background(), id, newID, expiration)
+}
+
+// RegenerateContext updates the session id and expiration with the new session id
+// of the the given current session id
+func (p *Provider) RegenerateContext(ctx context.Context, id, newID []byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	key := p.getSessionKey(id)
 
 	data, found := p.db.LoadAndDelete(key)
 	if found && data != nil {
-		item := data.(*item)
-		item.lastActiveTime = time.Now().UnixNano()
-		item.expiration = expiration
+		newItem := acquireItem()
+		*newItem = *data.(*item)
+		newItem.lastActiveTime = time.Now().UnixNano()
+		newItem.expiration = expiration
 
 		newKey := p.getSessionKey(newID)
 
-		p.db.Store(newKey, item)
+		p.db.Store(newKey, newItem)
 	}
 
 	return nil
 }
 
-func (p *Provider) destroy(key string) error {
-	val, found := p.db.LoadAndDelete(key)
-	if !found || val == nil {
-		return nil
+// Touch updates the expiration of the given session id
+// without rewriting its data
+func (p *Provider) Touch(id []byte, expiration time.Duration) error {
+	return p.TouchContext(context.Background(), id, expiration)
+}
+
+// TouchContext updates the expiration of the given session id
+// without rewriting its data
+func (p *Provider) TouchContext(ctx context.Context, id []byte, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key := p.getSessionKey(id)
+
+	for {
+		val, found := p.db.Load(key)
+		if !found || val == nil {
+			return nil
+		}
+
+		// The stored items are read concurrently, so a copy is stored instead of changing it
+		newItem := acquireItem()
+		*newItem = *val.(*item)
+		newItem.lastActiveTime = time.Now().UnixNano()
+		newItem.expiration = expiration
+
+		if p.db.CompareAndSwap(key, val, newItem) {
+			return nil
+		}
+
+		// Saved in the meantime
+		releaseItem(newItem)
+	}
+}
+
+// Lock acquires the lock of the given session id for the given ttl,
+// and returns a fencing token which is greater each time the lock is acquired
+//
+// The locks are only held in the current process.
+// Returns session.ErrSessionLocked if the lock is held by another owner
+func (p *Provider) Lock(ctx context.Context, id []byte, ttl time.Duration) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	key := p.getSessionKey(id)
+	now := time.Now()
+
+	p.locksMu.Lock()
+	defer p.locksMu.Unlock()
+
+	if l, found := p.locks[key]; found && now.Before(l.expiresAt) {
+		return 0, session.ErrSessionLocked
+	}
+
+	p.lastToken++
+	p.locks[string(id)] = lock{token: p.lastToken, expiresAt: now.Add(ttl)}
+
+	return p.lastToken, nil
+}
+
+// Unlock releases the lock of the given session id if it's still held with the given token
+func (p *Provider) Unlock(ctx context.Context, id []byte, token uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	key := p.getSessionKey(id)
+
+	p.locksMu.Lock()
+	defer p.locksMu.Unlock()
+
+	if l, found := p.locks[key]; found && l.token == token {
+		delete(p.locks, key)
+	}
+
+	return nil
+}
+
+// BindUser adds the given session id to the sessions of the given user id
+func (p *Provider) BindUser(ctx context.Context, id, userID []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	releaseItem(val.(*item))
+	p.usersMu.Lock()
+	defer p.usersMu.Unlock()
+
+	ids := p.users[string(userID)]
+	if ids == nil {
+		ids = make(map[string]struct{})
+		p.users[string(userID)] = ids
+	}
+
+	ids[string(id)] = struct{}{}
+
+	return nil
+}
+
+// UserSessions returns the ids of the sessions of the given user id,
+// excluding the expired and destroyed ones
+func (p *Provider) UserSessions(ctx context.Context, userID []byte) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixNano()
+
+	p.usersMu.Lock()
+	defer p.usersMu.Unlock()
+
+	ids := p.users[string(userID)]
+	result := make([][]byte, 0, len(ids))
+
+	for id := range ids {
+		val, found := p.db.Load(id)
+		if !found || val == nil || p.isExpired(val.(*item), now) {
+			delete(ids, id)
+			continue
+		}
+
+		result = append(result, []byte(id))
+	}
+
+	if len(ids) == 0 {
+		delete(p.users, string(userID))
+	}
+
+	return result, nil
+}
+
+// Scan returns up to limit sessions from the given cursor, and the cursor of the next ones
+//
+// The sessions are sorted by id, and the cursor is the id of the last returned session
+func (p *Provider) Scan(ctx context.Context, cursor string, limit int) ([]session.SessionInfo, string, error) {
+	var infos []session.SessionInfo
+
+	now := time.Now().UnixNano()
+
+	p.db.Range(func(key, value interface{}) bool {
+		// The expired sessions are not deleted until the next GC
+		if id := key.(string); id > cursor && !p.isExpired(value.(*item), now) {
+			item := value.(*item)
+
+			info := session.SessionInfo{
+				ID:         []byte(id),
+				Size:       len(item.data),
+				LastActive: time.Unix(0, item.lastActiveTime),
+			}
+
+			if item.expiration != 0 {
+				info.ExpiresAt = info.LastActive.Add(item.expiration)
+			}
+
+			infos = append(infos, info)
+		}
+
+		return ctx.Err() == nil
+	})
+
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return string(infos[i].ID) < string(infos[j].ID)
+	})
+
+	if len(infos) <= limit {
+		return infos, "", nil
+	}
+
+	infos = infos[:limit]
+
+	return infos, string(infos[limit-1].ID), nil
+}
+
+func (p *Provider) isExpired(item *item, now int64) bool {
+	return item.expiration != 0 && now >= (item.lastActiveTime+item.expiration.Nanoseconds())
+}
+
+func (p *Provider) destroy(key string) error {
+	// The deleted item is not released to the pool, since it could be read concurrently
+	p.db.Delete(key)
 
 	return nil
 }
 
 // Destroy destroys the session from the given id
 func (p *Provider) Destroy(id []byte) error {
+	return p.DestroyContext(context.Background(), id)
+}
+
+// DestroyContext destroys the session from the given id
+func (p *Provider) DestroyContext(ctx context.Context, id []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	key := p.getSessionKey(id)
 
 	return p.destroy(key)
 }
 
 // Count returns the total of stored sessions
-func (p *Provider) Count() (count int) {
+func (p *Provider) Count() int {
+	return p.CountContext(context.Background())
+}
+
+// CountContext returns the total of stored sessions
+func (p *Provider) CountContext(ctx context.Context) (count int) {
 	p.db.Range(func(_, _ interface{}) bool {
 		count++
 
-		return true
+		return ctx.Err() == nil
 	})
 
 	return count
@@ -119,23 +411,40 @@ func (p *Provider) NeedGC() bool {
 	return true
 }
 
+// SetExpirationHandler sets the function called by GC with the id of each expired session
+func (p *Provider) SetExpirationHandler(handler func(id []byte)) {
+	p.expirationHandler = handler
+}
+
 // GC destroys the expired sessions
 func (p *Provider) GC() error {
+	return p.GCContext(context.Background())
+}
+
+// GCContext destroys the expired sessions
+func (p *Provider) GCContext(ctx context.Context) error {
 	now := time.Now().UnixNano()
 
 	p.db.Range(func(key, value interface{}) bool {
 		item := value.(*item)
 
-		if item.expiration == 0 {
-			return true
-		}
-
-		if now >= (item.lastActiveTime + item.expiration.Nanoseconds()) {
-			_ = p.destroy(key.(string))
+		// Not deleted if it has been touched or saved in the meantime
+		if p.isExpired(item, now) && p.db.CompareAndDelete(key, value) {
+			if p.expirationHandler != nil {
+				p.expirationHandler([]byte(key.(string)))
+			}
 		}
 
-		return true
+		return ctx.Err() == nil
 	})
 
-	return nil
+	p.locksMu.Lock()
+	for key, l := range p.locks {
+		if now >= l.expiresAt.UnixNano() {
+			delete(p.locks, key)
+		}
+	}
+	p.locksMu.Unlock()
+
+	return ctx.Err()
 }
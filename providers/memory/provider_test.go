@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/session/v2/internal/providertest"
+)
+
+func newTestProvider(t *testing.T) *Provider {
+	t.Helper()
+
+	p, err := New(Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return p
+}
+
+func TestProvider_GetNotFound(t *testing.T) {
+	providertest.GetNotFound(t, newTestProvider(t))
+}
+
+func TestProvider_StrictSessionID(t *testing.T) {
+	providertest.StrictSessionID(t, newTestProvider(t))
+}
+
+func TestProvider_CompareAndSave(t *testing.T) {
+	providertest.CompareAndSave(t, newTestProvider(t))
+}
+
+func TestProvider_Lock(t *testing.T) {
+	providertest.Lock(t, newTestProvider(t))
+}
+
+func TestProvider_UserIndex(t *testing.T) {
+	providertest.UserIndex(t, newTestProvider(t))
+}
+
+func TestProvider_Scan(t *testing.T) {
+	providertest.Scan(t, newTestProvider(t))
+}
+
+func TestProvider_ExpirationHandler(t *testing.T) {
+	providertest.ExpirationHandler(t, newTestProvider(t))
+}
+
+func TestProvider_Flashes(t *testing.T) {
+	providertest.Flashes(t, newTestProvider(t))
+}
+
+func TestProvider_TouchConcurrentGC(t *testing.T) {
+	p := newTestProvider(t)
+
+	id := []byte("touch-id")
+
+	if err := p.Save(id, []byte("data"), time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 1000; i++ {
+			if err := p.Touch(id, time.Hour); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 1000; i++ {
+			if err := p.GC(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			_, _ = p.Get(id)
+		}
+	}()
+
+	wg.Wait()
+
+	// A touched session is not deleted by GC
+	if err := p.Save(id, []byte("data"), time.Nanosecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := p.Touch(id, time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := p.GC(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := p.Get(id); err != nil {
+		t.Errorf("Provider.Get() of a touched session error == %v, want nil", err)
+	}
+}
@@ -12,10 +12,28 @@ type Config struct{}
 type Provider struct {
 	config Config
 	db     sync.Map
+
+	locksMu   sync.Mutex
+	locks     map[string]lock
+	lastToken uint64
+
+	usersMu sync.Mutex
+	users   map[string]map[string]struct{}
+
+	expirationHandler func(id []byte)
+}
+
+type lock struct {
+	token     uint64
+	expiresAt time.Time
 }
 
+// item is a stored session
+//
+// The stored items are read concurrently, so they are replaced instead of changed
 type item struct {
 	data           []byte
 	lastActiveTime int64
 	expiration     time.Duration
+	version        uint64
 }
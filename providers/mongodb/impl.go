@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/fasthttp/session/v2"
 	"github.com/savsgio/gotils/strconv"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -15,6 +16,16 @@ func (p *Provider) getCollection() *mongo.Collection {
 	return p.db.Database(p.config.Database).Collection(p.config.Collection)
 }
 
+// getLockCollection returns the collection of the session locks
+func (p *Provider) getLockCollection() *mongo.Collection {
+	return p.db.Database(p.config.Database).Collection(p.config.Collection + "_lock")
+}
+
+// getLockTokenCollection returns the collection of the counter of the lock fencing tokens
+func (p *Provider) getLockTokenCollection() *mongo.Collection {
+	return p.db.Database(p.config.Database).Collection(p.config.Collection + "_lock_token")
+}
+
 func (p *Provider) getSessionId(sessionID []byte) string {
 	return strconv.B2S(sessionID)
 }
@@ -23,16 +34,32 @@ func (p *Provider) getFilter(id []byte) primitive.D {
 	return bson.D{{Key: "sessionId", Value: p.getSessionId(id)}}
 }
 
+// expiresAt returns the time when a session saved now with the given expiration expires,
+// or 0 if it never expires
+func expiresAt(expiration time.Duration) int64 {
+	if expiration <= 0 {
+		return 0
+	}
+
+	return time.Now().Add(expiration).UnixNano()
+}
+
 // Get returns the session value stored in the database.
 func (p *Provider) Get(id []byte) ([]byte, error) {
+	return p.GetContext(context.Background(), id)
+}
 
+// GetContext returns the session value stored in the database.
+//
+// Returns session.ErrSessionNotFound if the session does not exist
+func (p *Provider) GetContext(ctx context.Context, id []byte) ([]byte, error) {
 	var i item
-	err := p.getCollection().FindOne(context.Background(), p.getFilter(id)).Decode(&i)
+	err := p.getCollection().FindOne(ctx, p.getFilter(id)).Decode(&i)
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			// This error means your query did not match any documents.
-			return nil, nil // Session not found
+			return nil, session.ErrSessionNotFound
 		}
 		return nil, err
 	}
@@ -42,40 +69,297 @@ func (p *Provider) Get(id []byte) ([]byte, error) {
 
 // Save saves the session data and expiration from the given session id
 func (p *Provider) Save(id []byte, data []byte, expiration time.Duration) error {
+	return p.SaveContext(context.Background(), id, data, expiration)
+}
+
+// SaveContext saves the session data and expiration from the given session id
+func (p *Provider) SaveContext(ctx context.Context, id []byte, data []byte, expiration time.Duration) error {
 	sessionId := p.getSessionId(id)
 
-	_, err := p.getCollection().UpdateOne(context.Background(), p.getFilter(id), bson.D{
+	_, err := p.getCollection().UpdateOne(ctx, p.getFilter(id), bson.D{
 		{Key: "$set", Value: bson.D{
 			{Key: "sessionId", Value: sessionId},
 			{Key: "data", Value: data},
 			{Key: "expiration", Value: expiration},
+			{Key: "expiresAt", Value: expiresAt(expiration)},
+		}},
+		{Key: "$inc", Value: bson.D{
+			{Key: "version", Value: 1},
 		}},
 	}, options.Update().SetUpsert(true))
 
 	return err
 }
 
+// GetVersion returns the data and the version of the given session id
+//
+// Returns session.ErrSessionNotFound if the session does not exist
+func (p *Provider) GetVersion(ctx context.Context, id []byte) ([]byte, uint64, error) {
+	var i item
+	err := p.getCollection().FindOne(ctx, p.getFilter(id)).Decode(&i)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, 0, session.ErrSessionNotFound
+		}
+		return nil, 0, err
+	}
+
+	return i.Data, uint64(i.Version), nil
+}
+
+// CompareAndSave saves the session data and expiration from the given session id
+// only if its stored version is the given one, and returns the new version
+//
+// Returns session.ErrConcurrentModification if the stored version is another one
+func (p *Provider) CompareAndSave(ctx context.Context, id []byte, data []byte, version uint64, expiration time.Duration) (uint64, error) {
+	sessionId := p.getSessionId(id)
+
+	var versionFilter interface{} = int64(version)
+	if version == 0 {
+		// The sessions stored before have no version
+		versionFilter = bson.D{{Key: "$in", Value: bson.A{int64(0), nil}}}
+	}
+
+	result, err := p.getCollection().UpdateOne(ctx, bson.D{
+		{Key: "sessionId", Value: sessionId},
+		{Key: "version", Value: versionFilter},
+	}, bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "data", Value: data},
+			{Key: "expiration", Value: expiration},
+			{Key: "expiresAt", Value: expiresAt(expiration)},
+		}},
+		{Key: "$inc", Value: bson.D{
+			{Key: "version", Value: 1},
+		}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if result.MatchedCount > 0 {
+		return version + 1, nil
+	}
+
+	if version != 0 {
+		return 0, session.ErrConcurrentModification
+	}
+
+	// Not stored yet, nothing is inserted if another request has stored it in the meantime
+	result, err = p.getCollection().UpdateOne(ctx, p.getFilter(id), bson.D{
+		{Key: "$setOnInsert", Value: bson.D{
+			{Key: "sessionId", Value: sessionId},
+			{Key: "data", Value: data},
+			{Key: "expiration", Value: expiration},
+			{Key: "expiresAt", Value: expiresAt(expiration)},
+			{Key: "version", Value: int64(1)},
+		}},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		return 0, err
+	}
+
+	if result.UpsertedCount == 0 {
+		return 0, session.ErrConcurrentModification
+	}
+
+	return 1, nil
+}
+
+// Touch updates the expiration of the given session id
+// without rewriting its data
+func (p *Provider) Touch(id []byte, expiration time.Duration) error {
+	return p.TouchContext(context.Background(), id, expiration)
+}
+
+// TouchContext updates the expiration of the given session id
+// without rewriting its data
+func (p *Provider) TouchContext(ctx context.Context, id []byte, expiration time.Duration) error {
+	_, err := p.getCollection().UpdateOne(ctx, p.getFilter(id), bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "expiration", Value: expiration},
+			{Key: "expiresAt", Value: expiresAt(expiration)},
+		}},
+	})
+
+	return err
+}
+
 // Destroy destroys the session from the given id
 func (p *Provider) Destroy(id []byte) error {
+	return p.DestroyContext(context.Background(), id)
+}
 
-	_, err := p.getCollection().DeleteOne(context.Background(), p.getFilter(id))
+// DestroyContext destroys the session from the given id
+func (p *Provider) DestroyContext(ctx context.Context, id []byte) error {
+	_, err := p.getCollection().DeleteOne(ctx, p.getFilter(id))
 
 	return err
 }
 
+// nextLockToken increments the counter of the lock fencing tokens and returns its new value
+func (p *Provider) nextLockToken(ctx context.Context) (int64, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var counter lockToken
+
+	err := p.getLockTokenCollection().FindOneAndUpdate(ctx,
+		bson.D{{Key: "_id", Value: lockTokenID}},
+		bson.D{{Key: "$inc", Value: bson.D{{Key: "token", Value: int64(1)}}}},
+		opts,
+	).Decode(&counter)
+
+	return counter.Token, err
+}
+
+// Lock acquires the lock of the given session id for the given ttl,
+// and returns a fencing token which is greater each time the lock is acquired
+//
+// The lock is a document of the lock collection, and the token is taken from the counter of the lock token collection.
+// Returns session.ErrSessionLocked if the lock is held by another owner
+func (p *Provider) Lock(ctx context.Context, id []byte, ttl time.Duration) (uint64, error) {
+	sessionId := p.getSessionId(id)
+	now := time.Now().UnixNano()
+
+	// Release the expired lock
+	_, err := p.getLockCollection().DeleteOne(ctx, bson.D{
+		{Key: "_id", Value: sessionId},
+		{Key: "expiresAt", Value: bson.D{{Key: "$lte", Value: now}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	token, err := p.nextLockToken(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = p.getLockCollection().InsertOne(ctx, lock{
+		SessionId: sessionId,
+		Token:     token,
+		ExpiresAt: now + ttl.Nanoseconds(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return 0, session.ErrSessionLocked
+	} else if err != nil {
+		return 0, err
+	}
+
+	return uint64(token), nil
+}
+
+// Unlock releases the lock of the given session id if it's still held with the given token
+func (p *Provider) Unlock(ctx context.Context, id []byte, token uint64) error {
+	_, err := p.getLockCollection().DeleteOne(ctx, bson.D{
+		{Key: "_id", Value: p.getSessionId(id)},
+		{Key: "token", Value: int64(token)},
+	})
+
+	return err
+}
+
+// BindUser adds the given session id to the sessions of the given user id
+func (p *Provider) BindUser(ctx context.Context, id, userID []byte) error {
+	_, err := p.getCollection().UpdateOne(ctx, p.getFilter(id), bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "userId", Value: strconv.B2S(userID)},
+		}},
+	})
+
+	return err
+}
+
+// UserSessions returns the ids of the sessions of the given user id,
+// excluding the expired and destroyed ones
+func (p *Provider) UserSessions(ctx context.Context, userID []byte) ([][]byte, error) {
+	filter := bson.D{
+		{Key: "userId", Value: strconv.B2S(userID)},
+		notExpiredFilter(time.Now().UnixNano()),
+	}
+
+	cursor, err := p.getCollection().Find(ctx, filter, options.Find().SetProjection(bson.D{{Key: "sessionId", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []item
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	ids := make([][]byte, len(items))
+	for i := range items {
+		ids[i] = []byte(items[i].SessionId)
+	}
+
+	return ids, nil
+}
+
+// Scan returns up to limit sessions ordered by id after the given cursor,
+// and the cursor of the next ones
+func (p *Provider) Scan(ctx context.Context, cursor string, limit int) ([]session.SessionInfo, string, error) {
+	filter := bson.D{
+		{Key: "sessionId", Value: bson.D{{Key: "$gt", Value: cursor}}},
+		notExpiredFilter(time.Now().UnixNano()),
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "sessionId", Value: 1}}).SetLimit(int64(limit))
+
+	result, err := p.getCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var items []item
+	if err := result.All(ctx, &items); err != nil {
+		return nil, "", err
+	}
+
+	infos := make([]session.SessionInfo, len(items))
+
+	for i := range items {
+		infos[i] = session.SessionInfo{
+			ID:   []byte(items[i].SessionId),
+			Size: len(items[i].Data),
+		}
+
+		if items[i].ExpiresAt > 0 {
+			infos[i].ExpiresAt = time.Unix(0, items[i].ExpiresAt)
+			infos[i].LastActive = infos[i].ExpiresAt.Add(-items[i].Expiration)
+		}
+	}
+
+	next := ""
+	if len(items) == limit {
+		next = items[len(items)-1].SessionId
+	}
+
+	return infos, next, nil
+}
+
 // Regenerate updates the session id and expiration with the new session id
 // of the the given current session id
 func (p *Provider) Regenerate(id []byte, newID []byte, expiration time.Duration) error {
+	return p.RegenerateContext(context.Background(), id, newID, expiration)
+}
+
+// RegenerateContext updates the session id and expiration with the new session id
+// of the the given current session id
+func (p *Provider) RegenerateContext(ctx context.Context, id []byte, newID []byte, expiration time.Duration) error {
 	var i item
-	err := p.getCollection().FindOneAndDelete(context.Background(), p.getFilter(id)).Decode(&i)
+	err := p.getCollection().FindOneAndDelete(ctx, p.getFilter(id)).Decode(&i)
 	if err != nil {
 		return err
 	}
 
-	_, err = p.getCollection().InsertOne(context.Background(), bson.D{
+	_, err = p.getCollection().InsertOne(ctx, bson.D{
 		{Key: "sessionId", Value: p.getSessionId(newID)},
 		{Key: "data", Value: i.Data},
 		{Key: "expiration", Value: expiration},
+		{Key: "expiresAt", Value: expiresAt(expiration)},
+		{Key: "version", Value: i.Version},
+		{Key: "userId", Value: i.UserId},
 	})
 
 	return err
@@ -83,7 +367,12 @@ func (p *Provider) Regenerate(id []byte, newID []byte, expiration time.Duration)
 
 // Count returns the total of stored sessions
 func (p *Provider) Count() int {
-	count, err := p.getCollection().CountDocuments(context.Background(), bson.D{})
+	return p.CountContext(context.Background())
+}
+
+// CountContext returns the total of stored sessions
+func (p *Provider) CountContext(ctx context.Context) int {
+	count, err := p.getCollection().CountDocuments(ctx, bson.D{})
 
 	if err != nil {
 		return 0
@@ -99,9 +388,36 @@ func (p *Provider) NeedGC() bool {
 
 // GC destroys the expired sessions
 func (p *Provider) GC() error {
-	filter := bson.D{{Key: "expiration", Value: bson.D{{Key: "$lt", Value: time.Now().Unix()}}}}
+	return p.GCContext(context.Background())
+}
+
+// notExpiredFilter returns the filter element of the sessions which are not expired at the given time
+func notExpiredFilter(now int64) primitive.E {
+	return primitive.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: "expiresAt", Value: bson.D{{Key: "$exists", Value: false}}}},
+		bson.D{{Key: "expiresAt", Value: 0}},
+		bson.D{{Key: "expiresAt", Value: bson.D{{Key: "$gt", Value: now}}}},
+	}}
+}
+
+// expiredFilter returns the filter of the documents which are expired at the given time,
+// excluding the ones which never expire
+func expiredFilter(now int64) primitive.D {
+	return bson.D{{Key: "expiresAt", Value: bson.D{
+		{Key: "$gt", Value: 0},
+		{Key: "$lte", Value: now},
+	}}}
+}
+
+// GCContext destroys the expired sessions and locks
+func (p *Provider) GCContext(ctx context.Context) error {
+	now := time.Now().UnixNano()
+
+	if _, err := p.getCollection().DeleteMany(ctx, expiredFilter(now)); err != nil {
+		return err
+	}
 
-	_, err := p.getCollection().DeleteMany(context.Background(), filter)
+	_, err := p.getLockCollection().DeleteMany(ctx, expiredFilter(now))
 
 	return err
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -39,5 +40,17 @@ func New(cfg Config) (*Provider, error) {
 		db:     client,
 	}
 
+	_, err = p.getCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return p, nil
 }
+
+// Close disconnects the mongodb client
+func (p *Provider) Close() error {
+	return p.db.Disconnect(context.Background())
+}
@@ -24,4 +24,21 @@ type item struct {
 	Data       []byte        `bson:"data"`
 	Expiration time.Duration `bson:"expiration"`
 	SessionId  string        `bson:"sessionId"`
+	Version    int64         `bson:"version"`
+	UserId     string        `bson:"userId,omitempty"`
+	ExpiresAt  int64         `bson:"expiresAt"`
+}
+
+// lockTokenID is the id of the document of the lock token collection
+const lockTokenID = "token"
+
+type lockToken struct {
+	ID    string `bson:"_id"`
+	Token int64  `bson:"token"`
+}
+
+type lock struct {
+	SessionId string `bson:"_id"`
+	Token     int64  `bson:"token"`
+	ExpiresAt int64  `bson:"expiresAt"`
 }
@@ -17,10 +17,32 @@ var (
 		data TEXT NOT NULL COMMENT 'Session data',
 		last_active BIGINT SIGNED NOT NULL DEFAULT '0' COMMENT 'Last active time',
 		expiration BIGINT SIGNED NOT NULL DEFAULT '0' COMMENT 'Expiration time',
+		version BIGINT SIGNED NOT NULL DEFAULT '0' COMMENT 'Data version',
+		user_id VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'User id',
 		PRIMARY KEY (id),
+		KEY user_id (user_id),
 		KEY last_active (last_active),
 		KEY expiration (expiration)
 	 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='session table';`,
+		`CREATE TABLE IF NOT EXISTS %s_lock (
+		id VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'Session id',
+		token BIGINT SIGNED NOT NULL DEFAULT '0' COMMENT 'Fencing token',
+		expires_at BIGINT SIGNED NOT NULL DEFAULT '0' COMMENT 'Expiration time',
+		PRIMARY KEY (id),
+		KEY expires_at (expires_at)
+	 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='session lock table';`,
+		`CREATE TABLE IF NOT EXISTS %s_lock_token (
+		id INT NOT NULL COMMENT 'Counter id',
+		token BIGINT SIGNED NOT NULL DEFAULT '0' COMMENT 'Last fencing token',
+		PRIMARY KEY (id)
+	 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='session lock token table';`,
+		"INSERT IGNORE INTO %s_lock_token (id, token) VALUES (1, 0);",
+	}
+
+	// addedColumns are added to the tables created by the previous versions
+	addedColumns = []sql.Column{
+		{Name: "version", SQLAdd: []string{"ALTER TABLE %s ADD COLUMN version BIGINT SIGNED NOT NULL DEFAULT '0' COMMENT 'Data version';"}},
+		{Name: "user_id", SQLAdd: []string{"ALTER TABLE %s ADD COLUMN user_id VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'User id', ADD KEY user_id (user_id);"}},
 	}
 )
 
@@ -34,18 +56,33 @@ func New(cfg Config) (*Provider, error) {
 	}
 
 	providerCfg := sql.ProviderConfig{
-		Driver:          "mysql",
-		DSN:             cfg.dsn(),
-		MaxIdleConns:    cfg.MaxIdleConns,
-		MaxOpenConns:    cfg.MaxOpenConns,
-		ConnMaxLifetime: cfg.ConnMaxLifetime,
-		SQLGet:          fmt.Sprintf("SELECT data FROM %s WHERE id=?", cfg.TableName),
-		SQLSave:         fmt.Sprintf("UPDATE %s SET data=?,last_active=?,expiration=? WHERE id=?", cfg.TableName),
-		SQLRegenerate:   fmt.Sprintf("UPDATE %s SET id=?,last_active=?,expiration=? WHERE id=?", cfg.TableName),
-		SQLCount:        fmt.Sprintf("SELECT count(id) as total FROM %s", cfg.TableName),
-		SQLDestroy:      fmt.Sprintf("DELETE FROM %s WHERE id=?", cfg.TableName),
-		SQLInsert:       fmt.Sprintf("INSERT INTO %s (id, data, last_active, expiration) VALUES (?,?,?,?)", cfg.TableName),
-		SQLGC:           fmt.Sprintf("DELETE FROM %s WHERE last_active+expiration<=? AND expiration<>0", cfg.TableName),
+		Driver:            "mysql",
+		DSN:               cfg.dsn(),
+		MaxIdleConns:      cfg.MaxIdleConns,
+		MaxOpenConns:      cfg.MaxOpenConns,
+		ConnMaxLifetime:   cfg.ConnMaxLifetime,
+		SQLGet:            fmt.Sprintf("SELECT data FROM %s WHERE id=?", cfg.TableName),
+		SQLGetVersion:     fmt.Sprintf("SELECT data,version FROM %s WHERE id=?", cfg.TableName),
+		SQLSave:           fmt.Sprintf("UPDATE %s SET data=?,last_active=?,expiration=?,version=version+1 WHERE id=?", cfg.TableName),
+		SQLCompareAndSave: fmt.Sprintf("UPDATE %s SET data=?,last_active=?,expiration=?,version=version+1 WHERE id=? AND version=?", cfg.TableName),
+		SQLTouch:          fmt.Sprintf("UPDATE %s SET last_active=?,expiration=? WHERE id=?", cfg.TableName),
+		SQLRegenerate:     fmt.Sprintf("UPDATE %s SET id=?,last_active=?,expiration=? WHERE id=?", cfg.TableName),
+		SQLCount:          fmt.Sprintf("SELECT count(id) as total FROM %s", cfg.TableName),
+		SQLDestroy:        fmt.Sprintf("DELETE FROM %s WHERE id=?", cfg.TableName),
+		SQLInsert:         fmt.Sprintf("INSERT INTO %s (id, data, last_active, expiration, version) VALUES (?,?,?,?,1)", cfg.TableName),
+		SQLLock:           fmt.Sprintf("INSERT INTO %s_lock (id, token, expires_at) VALUES (?,?,?)", cfg.TableName),
+		SQLLockGet:        fmt.Sprintf("SELECT token FROM %s_lock WHERE id=?", cfg.TableName),
+		SQLLockToken:      fmt.Sprintf("UPDATE %s_lock_token SET token=token+1 WHERE id=1", cfg.TableName),
+		SQLLockTokenGet:   fmt.Sprintf("SELECT token FROM %s_lock_token WHERE id=1", cfg.TableName),
+		SQLLockClean:      fmt.Sprintf("DELETE FROM %s_lock WHERE id=? AND expires_at<=?", cfg.TableName),
+		SQLUnlock:         fmt.Sprintf("DELETE FROM %s_lock WHERE id=? AND token=?", cfg.TableName),
+		SQLLockGC:         fmt.Sprintf("DELETE FROM %s_lock WHERE expires_at<=?", cfg.TableName),
+		SQLBindUser:       fmt.Sprintf("UPDATE %s SET user_id=? WHERE id=?", cfg.TableName),
+		SQLUserSessions:   fmt.Sprintf("SELECT id FROM %s WHERE user_id=? AND (expiration=0 OR last_active+expiration>?)", cfg.TableName),
+		SQLScan:           fmt.Sprintf("SELECT id, LENGTH(data), last_active, expiration FROM %s WHERE id>? AND (expiration=0 OR last_active+expiration>?) ORDER BY id LIMIT ?", cfg.TableName),
+		SQLGC:             fmt.Sprintf("DELETE FROM %s WHERE last_active+expiration<=? AND expiration<>0", cfg.TableName),
+		SQLGCExpired:      fmt.Sprintf("SELECT id FROM %s WHERE last_active+expiration<=? AND expiration<>0", cfg.TableName),
+		SQLGCDestroy:      fmt.Sprintf("DELETE FROM %s WHERE id=? AND last_active+expiration<=? AND expiration<>0", cfg.TableName),
 	}
 
 	provider, err := sql.NewProvider(providerCfg)
@@ -80,6 +117,10 @@ func (p *Provider) init() error {
 			return err
 		}
 	}
+	if err := p.AddColumns(p.config.TableName, addedColumns); err != nil {
+		p.Close()
+		return err
+	}
 
 	return nil
 }
@@ -16,10 +16,34 @@ var (
 		id VARCHAR(64) PRIMARY KEY NOT NULL DEFAULT '',
 		data TEXT NOT NULL,
 		last_active BIGINT NOT NULL DEFAULT '0',
-		expiration BIGINT NOT NULL DEFAULT '0'
+		expiration BIGINT NOT NULL DEFAULT '0',
+		version BIGINT NOT NULL DEFAULT '0',
+		user_id VARCHAR(64) NOT NULL DEFAULT ''
 	);`,
 		"CREATE INDEX IF NOT EXISTS last_active ON %s (last_active);",
 		"CREATE INDEX IF NOT EXISTS expiration ON %s (expiration);",
+		`CREATE TABLE IF NOT EXISTS %s_lock (
+		id VARCHAR(64) PRIMARY KEY NOT NULL DEFAULT '',
+		token BIGINT NOT NULL DEFAULT '0',
+		expires_at BIGINT NOT NULL DEFAULT '0'
+	);`,
+		"CREATE INDEX IF NOT EXISTS expires_at ON %s_lock (expires_at);",
+		`CREATE TABLE IF NOT EXISTS %s_lock_token (
+		id INTEGER PRIMARY KEY NOT NULL,
+		token BIGINT NOT NULL DEFAULT '0'
+	);`,
+		"INSERT INTO %s_lock_token (id, token) VALUES (1, 0) ON CONFLICT DO NOTHING;",
+	}
+
+	// addedColumns are added to the tables created by the previous versions
+	addedColumns = []sql.Column{
+		{Name: "version", SQLAdd: []string{"ALTER TABLE %s ADD COLUMN version BIGINT NOT NULL DEFAULT '0';"}},
+		{Name: "user_id", SQLAdd: []string{"ALTER TABLE %s ADD COLUMN user_id VARCHAR(64) NOT NULL DEFAULT '';"}},
+	}
+
+	// indexQueries index the added columns, so they are run after adding them
+	indexQueries = []string{
+		"CREATE INDEX IF NOT EXISTS user_id ON %s (user_id);",
 	}
 )
 
@@ -33,18 +57,33 @@ func New(cfg Config) (*Provider, error) {
 	}
 
 	providerCfg := sql.ProviderConfig{
-		Driver:          "postgres",
-		DSN:             cfg.dsn(),
-		MaxIdleConns:    cfg.MaxIdleConns,
-		MaxOpenConns:    cfg.MaxOpenConns,
-		ConnMaxLifetime: cfg.ConnMaxLifetime,
-		SQLGet:          fmt.Sprintf("SELECT data FROM %s WHERE id=$1", cfg.TableName),
-		SQLSave:         fmt.Sprintf("UPDATE %s SET data=$1,last_active=$2,expiration=$3 WHERE id=$4", cfg.TableName),
-		SQLRegenerate:   fmt.Sprintf("UPDATE %s SET id=$1,last_active=$2,expiration=$3 WHERE id=$4", cfg.TableName),
-		SQLCount:        fmt.Sprintf("SELECT count(id) as total FROM %s", cfg.TableName),
-		SQLDestroy:      fmt.Sprintf("DELETE FROM %s WHERE id=$1", cfg.TableName),
-		SQLInsert:       fmt.Sprintf("INSERT INTO %s (id, data, last_active, expiration) VALUES ($1,$2,$3,$4)", cfg.TableName),
-		SQLGC:           fmt.Sprintf("DELETE FROM %s WHERE last_active+expiration<=$1 AND expiration<>0", cfg.TableName),
+		Driver:            "postgres",
+		DSN:               cfg.dsn(),
+		MaxIdleConns:      cfg.MaxIdleConns,
+		MaxOpenConns:      cfg.MaxOpenConns,
+		ConnMaxLifetime:   cfg.ConnMaxLifetime,
+		SQLGet:            fmt.Sprintf("SELECT data FROM %s WHERE id=$1", cfg.TableName),
+		SQLGetVersion:     fmt.Sprintf("SELECT data,version FROM %s WHERE id=$1", cfg.TableName),
+		SQLSave:           fmt.Sprintf("UPDATE %s SET data=$1,last_active=$2,expiration=$3,version=version+1 WHERE id=$4", cfg.TableName),
+		SQLCompareAndSave: fmt.Sprintf("UPDATE %s SET data=$1,last_active=$2,expiration=$3,version=version+1 WHERE id=$4 AND version=$5", cfg.TableName),
+		SQLTouch:          fmt.Sprintf("UPDATE %s SET last_active=$1,expiration=$2 WHERE id=$3", cfg.TableName),
+		SQLRegenerate:     fmt.Sprintf("UPDATE %s SET id=$1,last_active=$2,expiration=$3 WHERE id=$4", cfg.TableName),
+		SQLCount:          fmt.Sprintf("SELECT count(id) as total FROM %s", cfg.TableName),
+		SQLDestroy:        fmt.Sprintf("DELETE FROM %s WHERE id=$1", cfg.TableName),
+		SQLInsert:         fmt.Sprintf("INSERT INTO %s (id, data, last_active, expiration, version) VALUES ($1,$2,$3,$4,1)", cfg.TableName),
+		SQLLock:           fmt.Sprintf("INSERT INTO %s_lock (id, token, expires_at) VALUES ($1,$2,$3)", cfg.TableName),
+		SQLLockGet:        fmt.Sprintf("SELECT token FROM %s_lock WHERE id=$1", cfg.TableName),
+		SQLLockToken:      fmt.Sprintf("UPDATE %s_lock_token SET token=token+1 WHERE id=1", cfg.TableName),
+		SQLLockTokenGet:   fmt.Sprintf("SELECT token FROM %s_lock_token WHERE id=1", cfg.TableName),
+		SQLLockClean:      fmt.Sprintf("DELETE FROM %s_lock WHERE id=$1 AND expires_at<=$2", cfg.TableName),
+		SQLUnlock:         fmt.Sprintf("DELETE FROM %s_lock WHERE id=$1 AND token=$2", cfg.TableName),
+		SQLLockGC:         fmt.Sprintf("DELETE FROM %s_lock WHERE expires_at<=$1", cfg.TableName),
+		SQLBindUser:       fmt.Sprintf("UPDATE %s SET user_id=$1 WHERE id=$2", cfg.TableName),
+		SQLUserSessions:   fmt.Sprintf("SELECT id FROM %s WHERE user_id=$1 AND (expiration=0 OR last_active+expiration>$2)", cfg.TableName),
+		SQLScan:           fmt.Sprintf("SELECT id, OCTET_LENGTH(data), last_active, expiration FROM %s WHERE id>$1 AND (expiration=0 OR last_active+expiration>$2) ORDER BY id LIMIT $3", cfg.TableName),
+		SQLGC:             fmt.Sprintf("DELETE FROM %s WHERE last_active+expiration<=$1 AND expiration<>0", cfg.TableName),
+		SQLGCExpired:      fmt.Sprintf("SELECT id FROM %s WHERE last_active+expiration<=$1 AND expiration<>0", cfg.TableName),
+		SQLGCDestroy:      fmt.Sprintf("DELETE FROM %s WHERE id=$1 AND last_active+expiration<=$2 AND expiration<>0", cfg.TableName),
 	}
 
 	provider, err := sql.NewProvider(providerCfg)
@@ -80,6 +119,17 @@ func (p *Provider) init() error {
 			return err
 		}
 	}
+	if err := p.AddColumns(p.config.TableName, addedColumns); err != nil {
+		p.Close()
+		return err
+	}
+	for _, query := range indexQueries {
+		_, err := p.Exec(fmt.Sprintf(query, p.config.TableName))
+		if err != nil {
+			p.Close()
+			return err
+		}
+	}
 
 	return nil
 }
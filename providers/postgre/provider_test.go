@@ -0,0 +1,58 @@
+package postgre
+
+import (
+	"os"
+	"testing"
+
+	"github.com/fasthttp/session/v2/internal/providertest"
+)
+
+func newTestProvider(t *testing.T) *Provider {
+	t.Helper()
+
+	cfg := NewDefaultConfig()
+	cfg.Host = providertest.Env(t, "POSTGRES_HOST")
+	cfg.Username = "postgres"
+	cfg.Password = os.Getenv("POSTGRES_PASSWORD")
+	cfg.Database = "test"
+	cfg.TableName = "providertest"
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Cleanup(func() {
+		p.Close()
+	})
+
+	return p
+}
+
+func TestProvider_GetNotFound(t *testing.T) {
+	providertest.GetNotFound(t, newTestProvider(t))
+}
+
+func TestProvider_StrictSessionID(t *testing.T) {
+	providertest.StrictSessionID(t, newTestProvider(t))
+}
+
+func TestProvider_CompareAndSave(t *testing.T) {
+	providertest.CompareAndSave(t, newTestProvider(t))
+}
+
+func TestProvider_Lock(t *testing.T) {
+	providertest.Lock(t, newTestProvider(t))
+}
+
+func TestProvider_UserIndex(t *testing.T) {
+	providertest.UserIndex(t, newTestProvider(t))
+}
+
+func TestProvider_Scan(t *testing.T) {
+	providertest.Scan(t, newTestProvider(t))
+}
+
+func TestProvider_ExpirationHandler(t *testing.T) {
+	providertest.ExpirationHandler(t, newTestProvider(t))
+}
@@ -8,6 +8,7 @@ import (
 var (
 	ErrConfigAddrEmpty       = errors.New("Config Addr must not be empty")
 	ErrConfigMasterNameEmpty = errors.New("Config MasterName must not be empty")
+	ErrInvalidScanCursor     = errors.New("Invalid scan cursor")
 )
 
 func newErrRedisConnection(err error) error {
@@ -5,12 +5,19 @@ package redis
 
 import (
 	"context"
+	"time"
 
+	"github.com/fasthttp/session/v2"
 	"github.com/redis/go-redis/v9"
 )
 
 var all = []byte("*")
 
+var (
+	compareAndSaveScript = redis.NewScript(compareAndSaveScriptSource)
+	unlockScript         = redis.NewScript(unlockScriptSource)
+)
+
 // New returns a new configured redis provider
 func New(cfg Config) (*Provider, error) {
 	if cfg.Addr == "" {
@@ -58,6 +65,9 @@ func New(cfg Config) (*Provider, error) {
 	p := &Provider{
 		keyPrefix: cfg.KeyPrefix,
 		db:        db,
+		dbIndex:   cfg.DB,
+
+		expirationNotifications: cfg.ExpirationNotifications,
 	}
 
 	return p, nil
@@ -112,6 +122,9 @@ func NewFailover(cfg FailoverConfig) (*Provider, error) {
 	p := &Provider{
 		keyPrefix: cfg.KeyPrefix,
 		db:        db,
+		dbIndex:   cfg.DB,
+
+		expirationNotifications: cfg.ExpirationNotifications,
 	}
 
 	return p, nil
@@ -168,6 +181,9 @@ func NewFailoverCluster(cfg FailoverConfig) (*Provider, error) {
 	p := &Provider{
 		keyPrefix: cfg.KeyPrefix,
 		db:        db,
+		dbIndex:   cfg.DB,
+
+		expirationNotifications: cfg.ExpirationNotifications,
 	}
 
 	return p, nil
@@ -175,13 +191,76 @@ func NewFailoverCluster(cfg FailoverConfig) (*Provider, error) {
 
 // Get returns the data of the given session id
 func (p *Provider) Get(id []byte) ([]byte, error) {
+	return p.GetContext(context.Background(), id)
+}
+
+// GetContext returns the data of the given session id
+//
+// Returns session.ErrSessionNotFound if the session does not exist
+func (p *Provider) GetContext(ctx context.Context, id []byte) ([]byte, error) {
 	key := p.getRedisSessionKey(id)
 
-	reply, err := p.db.Get(context.Background(), key).Bytes()
-	if err != nil && err != redis.Nil {
+	reply, err := p.db.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, session.ErrSessionNotFound
+	} else if err != nil {
 		return nil, err
 	}
 
 	return reply, nil
+}
+
+// getUserID returns the user id which the given session id is bound to,
+// or nil if it's not bound
+func (p *Provider) getUserID(ctx context.Context, id []byte) ([]byte, error) {
+	reply, err := p.db.Get(ctx, p.getRedisSessionUserKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+func (p *Provider) compareAndSave(ctx context.Context, key string, data []byte, expected string, expiration time.Duration) (bool, error) {
+	saved, err := compareAndSaveScript.Run(ctx, p.db, []string{key}, data, expected, expiration.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return saved == 1, nil
+}
+
+// SetExpirationHandler subscribes to the expired keys notifications if ExpirationNotifications is enabled,
+// and calls the given function with the id of each expired session
+func (p *Provider) SetExpirationHandler(handler func(id []byte)) {
+	if !p.expirationNotifications {
+		return
+	}
+
+	subscriber, ok := p.db.(interface {
+		Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+	})
+	if !ok {
+		return
+	}
+
+	if p.expirationPubSub != nil {
+		p.expirationPubSub.Close()
+	}
+
+	p.expirationPubSub = subscriber.Subscribe(context.Background(), p.getExpiredChannel())
+
+	go func(pubsub *redis.PubSub) {
+		for msg := range pubsub.Channel() {
+			if id, ok := p.sessionIDFromKey(msg.Payload); ok {
+				handler(id)
+			}
+		}
+	}(p.expirationPubSub)
+}
 
+func (p *Provider) unlock(ctx context.Context, key string, token uint64) error {
+	return unlockScript.Run(ctx, p.db, []string{key}, token).Err()
 }
@@ -2,11 +2,52 @@ package redis
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/fasthttp/session/v2"
 	"github.com/valyala/bytebufferpool"
 )
 
+// compareAndSaveScriptSource sets the session data only if the beginning of the SHA-1 hash
+// of the stored data is the expected one, or if the session does not exist and nothing is expected
+//
+// KEYS[1]: session key, ARGV[1]: data, ARGV[2]: expected hash, ARGV[3]: expiration in milliseconds
+const compareAndSaveScriptSource = `
+local current = redis.call('GET', KEYS[1])
+if current then
+	if string.sub(redis.sha1hex(current), 1, 16) ~= ARGV[2] then
+		return 0
+	end
+elseif ARGV[2] ~= '' then
+	return 0
+end
+
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+
+return 1
+`
+
+// unlockScriptSource deletes the lock only if it's held with the given token
+//
+// KEYS[1]: lock key, ARGV[1]: token
+const unlockScriptSource = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+
+return 0
+`
+
 func (p *Provider) getRedisSessionKey(sessionID []byte) string {
 	key := bytebufferpool.Get()
 	key.SetString(p.keyPrefix)
@@ -20,30 +61,161 @@ func (p *Provider) getRedisSessionKey(sessionID []byte) string {
 	return keyStr
 }
 
+// getRedisLockKey returns the key of the session lock,
+// which is out of the session keys so the locks are not counted as sessions
+func (p *Provider) getRedisLockKey(sessionID []byte) string {
+	key := bytebufferpool.Get()
+	key.SetString(p.keyPrefix)
+	key.WriteString("-lock:")
+	key.Write(sessionID)
+
+	keyStr := key.String()
+
+	bytebufferpool.Put(key)
+
+	return keyStr
+}
+
+// getRedisLockTokenKey returns the key of the counter of the lock fencing tokens
+func (p *Provider) getRedisLockTokenKey() string {
+	return p.keyPrefix + "-lock"
+}
+
+// getRedisUserKey returns the key of the set of session ids of the given user id,
+// which is out of the session keys so the sets are not counted as sessions
+func (p *Provider) getRedisUserKey(userID []byte) string {
+	key := bytebufferpool.Get()
+	key.SetString(p.keyPrefix)
+	key.WriteString("-user:")
+	key.Write(userID)
+
+	keyStr := key.String()
+
+	bytebufferpool.Put(key)
+
+	return keyStr
+}
+
+// getRedisSessionUserKey returns the key of the user id which the given session id is bound to,
+// which is out of the session keys so it's not counted as a session
+func (p *Provider) getRedisSessionUserKey(sessionID []byte) string {
+	key := bytebufferpool.Get()
+	key.SetString(p.keyPrefix)
+	key.WriteString("-session-user:")
+	key.Write(sessionID)
+
+	keyStr := key.String()
+
+	bytebufferpool.Put(key)
+
+	return keyStr
+}
+
+// getExpiredChannel returns the channel of the keyspace notifications of the expired keys
+func (p *Provider) getExpiredChannel() string {
+	return "__keyevent@" + strconv.Itoa(p.dbIndex) + "__:expired"
+}
+
+// sessionIDFromKey returns the session id of the given key,
+// or false if it's not a session key
+func (p *Provider) sessionIDFromKey(key string) ([]byte, bool) {
+	prefix := p.getRedisSessionKey(nil)
+	if !strings.HasPrefix(key, prefix) {
+		return nil, false
+	}
+
+	return []byte(key[len(prefix):]), true
+}
+
 // Save saves the session data and expiration from the given session id
 func (p *Provider) Save(id, data []byte, expiration time.Duration) error {
+	return p.SaveContext(context.Background(), id, data, expiration)
+}
+
+// SaveContext saves the session data and expiration from the given session id
+func (p *Provider) SaveContext(ctx context.Context, id, data []byte, expiration time.Duration) error {
 	key := p.getRedisSessionKey(id)
 
-	return p.db.Set(context.Background(), key, data, expiration).Err()
+	return p.db.Set(ctx, key, data, expiration).Err()
+}
+
+// dataVersion returns the version of the given data,
+// which is the beginning of its SHA-1 hash as computed by the compare and save script
+func dataVersion(data []byte) uint64 {
+	sum := sha1.Sum(data)
+
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// GetVersion returns the data and the version of the given session id
+//
+// The version is derived from the data, so the sessions stored before
+// don't need to be migrated.
+// Returns session.ErrSessionNotFound if the session does not exist
+func (p *Provider) GetVersion(ctx context.Context, id []byte) ([]byte, uint64, error) {
+	data, err := p.GetContext(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return data, dataVersion(data), nil
+}
+
+// CompareAndSave saves the session data and expiration from the given session id
+// only if its stored version is the given one, and returns the new version
+//
+// The comparison and the save are executed atomically by a lua script.
+// Returns session.ErrConcurrentModification if the stored version is another one
+func (p *Provider) CompareAndSave(ctx context.Context, id, data []byte, version uint64, expiration time.Duration) (uint64, error) {
+	key := p.getRedisSessionKey(id)
+
+	expected := ""
+	if version != 0 {
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], version)
+
+		expected = hex.EncodeToString(buf[:])
+	}
+
+	saved, err := p.compareAndSave(ctx, key, data, expected, expiration)
+	if err != nil {
+		return 0, err
+	}
+
+	if !saved {
+		return 0, session.ErrConcurrentModification
+	}
+
+	return dataVersion(data), nil
 }
 
 // Regenerate updates the session id and expiration with the new session id
 // of the given current session id
 func (p *Provider) Regenerate(id, newID []byte, expiration time.Duration) error {
+	return p.RegenerateContext(context.Background(), id, newID, expiration)
+}
+
+// RegenerateContext updates the session id and expiration with the new session id
+// of the given current session id
+func (p *Provider) RegenerateContext(ctx context.Context, id, newID []byte, expiration time.Duration) error {
 	key := p.getRedisSessionKey(id)
 	newKey := p.getRedisSessionKey(newID)
 
-	exists, err := p.db.Exists(context.Background(), key).Result()
+	exists, err := p.db.Exists(ctx, key).Result()
 	if err != nil {
 		return err
 	}
 
 	if exists > 0 { // Exist
-		if err = p.db.Rename(context.Background(), key, newKey).Err(); err != nil {
+		if err = p.db.Rename(ctx, key, newKey).Err(); err != nil {
 			return err
 		}
 
-		if err = p.db.Expire(context.Background(), newKey, expiration).Err(); err != nil {
+		if err = p.db.Expire(ctx, newKey, expiration).Err(); err != nil {
+			return err
+		}
+
+		if err = p.rebindUser(ctx, id, newID); err != nil {
 			return err
 		}
 	}
@@ -51,16 +223,208 @@ func (p *Provider) Regenerate(id, newID []byte, expiration time.Duration) error
 	return nil
 }
 
+// Touch updates the expiration of the given session id
+// without rewriting its data
+func (p *Provider) Touch(id []byte, expiration time.Duration) error {
+	return p.TouchContext(context.Background(), id, expiration)
+}
+
+// TouchContext updates the expiration of the given session id
+// without rewriting its data
+func (p *Provider) TouchContext(ctx context.Context, id []byte, expiration time.Duration) error {
+	key := p.getRedisSessionKey(id)
+
+	if expiration == 0 { // Never expires
+		return p.db.Persist(ctx, key).Err()
+	}
+
+	return p.db.Expire(ctx, key, expiration).Err()
+}
+
+// Lock acquires the lock of the given session id for the given ttl,
+// and returns a fencing token which is greater each time the lock is acquired
+//
+// Returns session.ErrSessionLocked if the lock is held by another owner
+func (p *Provider) Lock(ctx context.Context, id []byte, ttl time.Duration) (uint64, error) {
+	token, err := p.db.Incr(ctx, p.getRedisLockTokenKey()).Uint64()
+	if err != nil {
+		return 0, err
+	}
+
+	ok, err := p.db.SetNX(ctx, p.getRedisLockKey(id), token, ttl).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok {
+		return 0, session.ErrSessionLocked
+	}
+
+	return token, nil
+}
+
+// Unlock releases the lock of the given session id if it's still held with the given token
+func (p *Provider) Unlock(ctx context.Context, id []byte, token uint64) error {
+	return p.unlock(ctx, p.getRedisLockKey(id), token)
+}
+
+// BindUser adds the given session id to the sessions of the given user id
+func (p *Provider) BindUser(ctx context.Context, id, userID []byte) error {
+	if err := p.db.SAdd(ctx, p.getRedisUserKey(userID), id).Err(); err != nil {
+		return err
+	}
+
+	return p.db.Set(ctx, p.getRedisSessionUserKey(id), userID, 0).Err()
+}
+
+// rebindUser moves the given session id to the given new id in the sessions of its user
+func (p *Provider) rebindUser(ctx context.Context, id, newID []byte) error {
+	userID, err := p.getUserID(ctx, id)
+	if err != nil || userID == nil {
+		return err
+	}
+
+	if err := p.unbindUser(ctx, id, userID); err != nil {
+		return err
+	}
+
+	return p.BindUser(ctx, newID, userID)
+}
+
+// unbindUser removes the given session id from the sessions of the given user id
+func (p *Provider) unbindUser(ctx context.Context, id, userID []byte) error {
+	if err := p.db.SRem(ctx, p.getRedisUserKey(userID), id).Err(); err != nil {
+		return err
+	}
+
+	return p.db.Del(ctx, p.getRedisSessionUserKey(id)).Err()
+}
+
+// UserSessions returns the ids of the sessions of the given user id,
+// excluding the expired and destroyed ones
+//
+// The destroyed and regenerated sessions are removed from the user set at once,
+// and the expired ones when the sessions of the user are listed
+func (p *Provider) UserSessions(ctx context.Context, userID []byte) ([][]byte, error) {
+	userKey := p.getRedisUserKey(userID)
+
+	members, err := p.db.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([][]byte, 0, len(members))
+
+	for _, member := range members {
+		id := []byte(member)
+
+		exists, err := p.db.Exists(ctx, p.getRedisSessionKey(id)).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		if exists == 0 {
+			if err := p.unbindUser(ctx, id, userID); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Scan returns up to limit sessions from the given cursor, and the cursor of the next ones
+//
+// The sessions are iterated with the redis SCAN command, so the number of returned sessions
+// is only approximated to the limit. The last active time is not known.
+func (p *Provider) Scan(ctx context.Context, cursor string, limit int) ([]session.SessionInfo, string, error) {
+	var redisCursor uint64
+
+	if cursor != "" {
+		c, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, "", ErrInvalidScanCursor
+		}
+
+		redisCursor = c
+	}
+
+	prefix := p.getRedisSessionKey(nil)
+
+	keys, next, err := p.db.Scan(ctx, redisCursor, prefix+"*", int64(limit)).Result()
+	if err != nil {
+		return nil, "", err
+	}
+
+	infos := make([]session.SessionInfo, 0, len(keys))
+
+	for _, key := range keys {
+		size, err := p.db.StrLen(ctx, key).Result()
+		if err != nil {
+			return nil, "", err
+		}
+
+		ttl, err := p.db.PTTL(ctx, key).Result()
+		if err != nil {
+			return nil, "", err
+		}
+
+		if ttl == -2 { // Not exist anymore
+			continue
+		}
+
+		info := session.SessionInfo{
+			ID:   []byte(key[len(prefix):]),
+			Size: int(size),
+		}
+
+		if ttl > 0 {
+			info.ExpiresAt = time.Now().Add(ttl)
+		}
+
+		infos = append(infos, info)
+	}
+
+	if next == 0 {
+		return infos, "", nil
+	}
+
+	return infos, strconv.FormatUint(next, 10), nil
+}
+
 // Destroy destroys the session from the given id
 func (p *Provider) Destroy(id []byte) error {
+	return p.DestroyContext(context.Background(), id)
+}
+
+// DestroyContext destroys the session from the given id
+func (p *Provider) DestroyContext(ctx context.Context, id []byte) error {
 	key := p.getRedisSessionKey(id)
 
-	return p.db.Del(context.Background(), key).Err()
+	if err := p.db.Del(ctx, key).Err(); err != nil {
+		return err
+	}
+
+	userID, err := p.getUserID(ctx, id)
+	if err != nil || userID == nil {
+		return err
+	}
+
+	return p.unbindUser(ctx, id, userID)
 }
 
 // Count returns the total of stored sessions
 func (p *Provider) Count() int {
-	reply, err := p.db.Keys(context.Background(), p.getRedisSessionKey(all)).Result()
+	return p.CountContext(context.Background())
+}
+
+// CountContext returns the total of stored sessions
+func (p *Provider) CountContext(ctx context.Context) int {
+	reply, err := p.db.Keys(ctx, p.getRedisSessionKey(all)).Result()
 	if err != nil {
 		return 0
 	}
@@ -75,5 +439,25 @@ func (p *Provider) NeedGC() bool {
 
 // GC destroys the expired sessions
 func (p *Provider) GC() error {
+	return p.GCContext(context.Background())
+}
+
+// GCContext destroys the expired sessions
+func (p *Provider) GCContext(ctx context.Context) error {
+	return nil
+}
+
+// Close closes the expired keys subscription and the redis client
+func (p *Provider) Close() error {
+	if p.expirationPubSub != nil {
+		if err := p.expirationPubSub.Close(); err != nil {
+			return err
+		}
+	}
+
+	if closer, ok := p.db.(io.Closer); ok {
+		return closer.Close()
+	}
+
 	return nil
 }
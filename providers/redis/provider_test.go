@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/fasthttp/session/v2/internal/providertest"
+)
+
+func newTestProvider(t *testing.T) *Provider {
+	t.Helper()
+
+	p, err := New(Config{
+		KeyPrefix: "providertest",
+		Addr:      providertest.Env(t, "REDIS_ADDR"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return p
+}
+
+func TestProvider_GetNotFound(t *testing.T) {
+	providertest.GetNotFound(t, newTestProvider(t))
+}
+
+func TestProvider_StrictSessionID(t *testing.T) {
+	providertest.StrictSessionID(t, newTestProvider(t))
+}
+
+func TestProvider_CompareAndSave(t *testing.T) {
+	providertest.CompareAndSave(t, newTestProvider(t))
+}
+
+func TestProvider_Lock(t *testing.T) {
+	providertest.Lock(t, newTestProvider(t))
+}
+
+func TestProvider_UserIndex(t *testing.T) {
+	providertest.UserIndex(t, newTestProvider(t))
+}
+
+func TestProvider_Scan(t *testing.T) {
+	providertest.Scan(t, newTestProvider(t))
+}
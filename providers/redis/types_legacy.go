@@ -95,6 +95,12 @@ type Config struct {
 
 	// Limiter interface used to implemented circuit breaker or rate limiter.
 	Limiter redis.Limiter
+
+	// ExpirationNotifications calls the OnExpire session hook with the expired sessions,
+	// subscribing to the keyspace notifications of the expired keys.
+	//
+	// The notifications must be enabled in the server, e.g. CONFIG SET notify-keyspace-events Ex
+	ExpirationNotifications bool
 }
 
 // FailoverConfig provider settings.
@@ -194,12 +200,22 @@ type FailoverConfig struct {
 
 	// TLS Config to use. When set TLS will be negotiated.
 	TLSConfig *tls.Config
+
+	// ExpirationNotifications calls the OnExpire session hook with the expired sessions,
+	// subscribing to the keyspace notifications of the expired keys.
+	//
+	// The notifications must be enabled in the server, e.g. CONFIG SET notify-keyspace-events Ex
+	ExpirationNotifications bool
 }
 
 // Provider backend manager
 type Provider struct {
 	keyPrefix string
 	db        redis.Cmdable
+	dbIndex   int
+
+	expirationNotifications bool
+	expirationPubSub        *redis.PubSub
 }
 
 // Logger implements the upstream redis internal Logger interface.
@@ -16,10 +16,34 @@ var (
 		id VARCHAR(64) PRIMARY KEY NOT NULL DEFAULT '',
 		data TEXT NOT NULL,
 		last_active BIGINT NOT NULL DEFAULT '0',
-		expiration BIGINT NOT NULL DEFAULT '0'
+		expiration BIGINT NOT NULL DEFAULT '0',
+		version BIGINT NOT NULL DEFAULT '0',
+		user_id VARCHAR(64) NOT NULL DEFAULT ''
 	);`,
 		"CREATE INDEX IF NOT EXISTS last_active ON %s (last_active);",
 		"CREATE INDEX IF NOT EXISTS expiration ON %s (expiration);",
+		`CREATE TABLE IF NOT EXISTS %s_lock (
+		id VARCHAR(64) PRIMARY KEY NOT NULL DEFAULT '',
+		token BIGINT NOT NULL DEFAULT '0',
+		expires_at BIGINT NOT NULL DEFAULT '0'
+	);`,
+		"CREATE INDEX IF NOT EXISTS expires_at ON %s_lock (expires_at);",
+		`CREATE TABLE IF NOT EXISTS %s_lock_token (
+		id INTEGER PRIMARY KEY NOT NULL,
+		token BIGINT NOT NULL DEFAULT '0'
+	);`,
+		"INSERT OR IGNORE INTO %s_lock_token (id, token) VALUES (1, 0);",
+	}
+
+	// addedColumns are added to the tables created by the previous versions
+	addedColumns = []sql.Column{
+		{Name: "version", SQLAdd: []string{"ALTER TABLE %s ADD COLUMN version BIGINT NOT NULL DEFAULT '0';"}},
+		{Name: "user_id", SQLAdd: []string{"ALTER TABLE %s ADD COLUMN user_id VARCHAR(64) NOT NULL DEFAULT '';"}},
+	}
+
+	// indexQueries index the added columns, so they are run after adding them
+	indexQueries = []string{
+		"CREATE INDEX IF NOT EXISTS user_id ON %s (user_id);",
 	}
 )
 
@@ -30,18 +54,33 @@ func New(cfg Config) (*Provider, error) {
 	}
 
 	providerCfg := sql.ProviderConfig{
-		Driver:          "sqlite3",
-		DSN:             cfg.DBPath,
-		MaxIdleConns:    cfg.MaxIdleConns,
-		MaxOpenConns:    cfg.MaxOpenConns,
-		ConnMaxLifetime: cfg.ConnMaxLifetime,
-		SQLGet:          fmt.Sprintf("SELECT data FROM %s WHERE id=?", cfg.TableName),
-		SQLSave:         fmt.Sprintf("UPDATE %s SET data=?,last_active=?,expiration=? WHERE id=?", cfg.TableName),
-		SQLRegenerate:   fmt.Sprintf("UPDATE %s SET id=?,last_active=?,expiration=? WHERE id=?", cfg.TableName),
-		SQLCount:        fmt.Sprintf("SELECT count(id) as total FROM %s", cfg.TableName),
-		SQLDestroy:      fmt.Sprintf("DELETE FROM %s WHERE id=?", cfg.TableName),
-		SQLInsert:       fmt.Sprintf("INSERT INTO %s (id, data, last_active, expiration) VALUES (?,?,?,?)", cfg.TableName),
-		SQLGC:           fmt.Sprintf("DELETE FROM %s WHERE last_active+expiration<=? AND expiration<>0", cfg.TableName),
+		Driver:            "sqlite3",
+		DSN:               cfg.DBPath,
+		MaxIdleConns:      cfg.MaxIdleConns,
+		MaxOpenConns:      cfg.MaxOpenConns,
+		ConnMaxLifetime:   cfg.ConnMaxLifetime,
+		SQLGet:            fmt.Sprintf("SELECT data FROM %s WHERE id=?", cfg.TableName),
+		SQLGetVersion:     fmt.Sprintf("SELECT data,version FROM %s WHERE id=?", cfg.TableName),
+		SQLSave:           fmt.Sprintf("UPDATE %s SET data=?,last_active=?,expiration=?,version=version+1 WHERE id=?", cfg.TableName),
+		SQLCompareAndSave: fmt.Sprintf("UPDATE %s SET data=?,last_active=?,expiration=?,version=version+1 WHERE id=? AND version=?", cfg.TableName),
+		SQLTouch:          fmt.Sprintf("UPDATE %s SET last_active=?,expiration=? WHERE id=?", cfg.TableName),
+		SQLRegenerate:     fmt.Sprintf("UPDATE %s SET id=?,last_active=?,expiration=? WHERE id=?", cfg.TableName),
+		SQLCount:          fmt.Sprintf("SELECT count(id) as total FROM %s", cfg.TableName),
+		SQLDestroy:        fmt.Sprintf("DELETE FROM %s WHERE id=?", cfg.TableName),
+		SQLInsert:         fmt.Sprintf("INSERT INTO %s (id, data, last_active, expiration, version) VALUES (?,?,?,?,1)", cfg.TableName),
+		SQLLock:           fmt.Sprintf("INSERT INTO %s_lock (id, token, expires_at) VALUES (?,?,?)", cfg.TableName),
+		SQLLockGet:        fmt.Sprintf("SELECT token FROM %s_lock WHERE id=?", cfg.TableName),
+		SQLLockToken:      fmt.Sprintf("UPDATE %s_lock_token SET token=token+1 WHERE id=1", cfg.TableName),
+		SQLLockTokenGet:   fmt.Sprintf("SELECT token FROM %s_lock_token WHERE id=1", cfg.TableName),
+		SQLLockClean:      fmt.Sprintf("DELETE FROM %s_lock WHERE id=? AND expires_at<=?", cfg.TableName),
+		SQLUnlock:         fmt.Sprintf("DELETE FROM %s_lock WHERE id=? AND token=?", cfg.TableName),
+		SQLLockGC:         fmt.Sprintf("DELETE FROM %s_lock WHERE expires_at<=?", cfg.TableName),
+		SQLBindUser:       fmt.Sprintf("UPDATE %s SET user_id=? WHERE id=?", cfg.TableName),
+		SQLUserSessions:   fmt.Sprintf("SELECT id FROM %s WHERE user_id=? AND (expiration=0 OR last_active+expiration>?)", cfg.TableName),
+		SQLScan:           fmt.Sprintf("SELECT id, LENGTH(CAST(data AS BLOB)), last_active, expiration FROM %s WHERE id>? AND (expiration=0 OR last_active+expiration>?) ORDER BY id LIMIT ?", cfg.TableName),
+		SQLGC:             fmt.Sprintf("DELETE FROM %s WHERE last_active+expiration<=? AND expiration<>0", cfg.TableName),
+		SQLGCExpired:      fmt.Sprintf("SELECT id FROM %s WHERE last_active+expiration<=? AND expiration<>0", cfg.TableName),
+		SQLGCDestroy:      fmt.Sprintf("DELETE FROM %s WHERE id=? AND last_active+expiration<=? AND expiration<>0", cfg.TableName),
 	}
 
 	provider, err := sql.NewProvider(providerCfg)
@@ -76,6 +115,17 @@ func (p *Provider) init() error {
 			return err
 		}
 	}
+	if err := p.AddColumns(p.config.TableName, addedColumns); err != nil {
+		p.Close()
+		return err
+	}
+	for _, query := range indexQueries {
+		_, err := p.Exec(fmt.Sprintf(query, p.config.TableName))
+		if err != nil {
+			p.Close()
+			return err
+		}
+	}
 
 	return nil
 }
@@ -0,0 +1,134 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/session/v2/internal/providertest"
+)
+
+func newTestProvider(t *testing.T) *Provider {
+	t.Helper()
+
+	cfg := NewConfigWith(filepath.Join(t.TempDir(), "session.db"), "session")
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Cleanup(func() {
+		p.Close()
+	})
+
+	return p
+}
+
+func TestProvider_GetNotFound(t *testing.T) {
+	providertest.GetNotFound(t, newTestProvider(t))
+}
+
+func TestProvider_StrictSessionID(t *testing.T) {
+	providertest.StrictSessionID(t, newTestProvider(t))
+}
+
+func TestProvider_CompareAndSave(t *testing.T) {
+	providertest.CompareAndSave(t, newTestProvider(t))
+}
+
+func TestProvider_Lock(t *testing.T) {
+	providertest.Lock(t, newTestProvider(t))
+}
+
+func TestProvider_LockTokenPersisted(t *testing.T) {
+	cfg := NewConfigWith(filepath.Join(t.TempDir(), "session.db"), "session")
+	id := []byte("lock-id")
+
+	lockToken := func() uint64 {
+		p, err := New(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer p.Close()
+
+		token, err := p.Lock(context.Background(), id, time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := p.Unlock(context.Background(), id, token); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		return token
+	}
+
+	token := lockToken()
+
+	// Reopened, as after a restart
+	if newToken := lockToken(); newToken <= token {
+		t.Errorf("Provider.Lock() token == %d, want greater than %d", newToken, token)
+	}
+}
+
+func TestProvider_UserIndex(t *testing.T) {
+	providertest.UserIndex(t, newTestProvider(t))
+}
+
+func TestProvider_Scan(t *testing.T) {
+	providertest.Scan(t, newTestProvider(t))
+}
+
+func TestProvider_ExpirationHandler(t *testing.T) {
+	providertest.ExpirationHandler(t, newTestProvider(t))
+}
+
+func TestProvider_AddColumns(t *testing.T) {
+	cfg := NewConfigWith(filepath.Join(t.TempDir(), "session.db"), "session")
+
+	db, err := sql.Open("sqlite3", cfg.DBPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Table created before the version and user_id columns
+	if _, err := db.Exec(`CREATE TABLE session (
+		id VARCHAR(64) PRIMARY KEY NOT NULL DEFAULT '',
+		data TEXT NOT NULL,
+		last_active BIGINT NOT NULL DEFAULT '0',
+		expiration BIGINT NOT NULL DEFAULT '0'
+	);`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := db.Exec("INSERT INTO session (id, data, last_active, expiration) VALUES ('old', 'data', 0, 0);"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	db.Close()
+
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer p.Close()
+
+	data, err := p.Get([]byte("old"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(data) != "data" {
+		t.Errorf("Provider.Get() == %q, want %q", data, "data")
+	}
+
+	if err := p.Save([]byte("old"), []byte("new data"), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	providertest.CompareAndSave(t, p)
+	providertest.UserIndex(t, p)
+}
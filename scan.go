@@ -0,0 +1,38 @@
+package session
+
+import "context"
+
+// Scan returns up to limit stored sessions from the given cursor, and the cursor of the next ones
+//
+// The first sessions are returned with an empty cursor,
+// and the next cursor is empty when there are no more sessions.
+// If the limit is not greater than 0, a default one is used
+func (s *Session) Scan(cursor string, limit int) ([]SessionInfo, string, error) {
+	return s.ScanContext(context.Background(), cursor, limit)
+}
+
+// ScanContext returns up to limit stored sessions from the given cursor, and the cursor of the next ones
+//
+// The first sessions are returned with an empty cursor,
+// and the next cursor is empty when there are no more sessions.
+// The provider call is canceled when the given context is done
+// or when the ProviderTimeout is reached
+func (s *Session) ScanContext(c context.Context, cursor string, limit int) ([]SessionInfo, string, error) {
+	p := s.provider.Load()
+	if p == nil {
+		return nil, "", ErrNotSetProvider
+	}
+
+	if p.scanner == nil {
+		return nil, "", ErrScanNotSupported
+	}
+
+	if limit <= 0 {
+		limit = defaultScanLimit
+	}
+
+	pctx, cancel := s.providerContext(c, nil)
+	defer cancel()
+
+	return p.scanner.Scan(pctx, cursor, limit)
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"testing"
+)
+
+func TestSession_ScanNotSupported(t *testing.T) {
+	s := New(Config{})
+
+	if _, _, err := s.Scan("", 10); err != ErrNotSetProvider {
+		t.Errorf("Expected error: %v", ErrNotSetProvider)
+	}
+
+	if err := s.SetProvider(new(mockProvider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, _, err := s.Scan("", 10); err != ErrScanNotSupported {
+		t.Errorf("Expected error: %v", ErrScanNotSupported)
+	}
+}
+
+func TestSession_Scan(t *testing.T) {
+	s := New(Config{})
+	provider := &mockScanner{
+		sessions: []SessionInfo{{ID: []byte("id1")}, {ID: []byte("id2")}, {ID: []byte("id3")}},
+	}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var ids []string
+
+	cursor := ""
+
+	for {
+		sessions, next, err := s.Scan(cursor, 2)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, info := range sessions {
+			ids = append(ids, string(info.ID))
+		}
+
+		if next == "" {
+			break
+		}
+
+		cursor = next
+	}
+
+	if len(ids) != 3 || ids[0] != "id1" || ids[2] != "id3" {
+		t.Errorf("Session.Scan() ids == %v, want [id1 id2 id3]", ids)
+	}
+}
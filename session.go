@@ -1,9 +1,12 @@
 package session
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
-	"sync"
 	"time"
 
 	"github.com/valyala/fasthttp"
@@ -15,12 +18,14 @@ var (
 
 // New returns a configured manager
 func New(cfg Config) *Session {
-	cfg.cookieLen = defaultCookieLen
-
 	if cfg.CookieName == "" {
 		cfg.CookieName = defaultSessionKeyName
 	}
 
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = defaultCookiePath
+	}
+
 	if cfg.GCLifetime == 0 {
 		cfg.GCLifetime = defaultGCLifetime
 	}
@@ -33,14 +38,18 @@ func New(cfg Config) *Session {
 		cfg.SessionNameInHTTPHeader = defaultSessionKeyName
 	}
 
-	if cfg.SessionIDGeneratorFunc == nil {
-		cfg.SessionIDGeneratorFunc = cfg.defaultSessionIDGenerator
+	if cfg.SessionIDLength == 0 {
+		cfg.SessionIDLength = defaultSessionIDLength
 	}
 
 	if cfg.IsSecureFunc == nil {
 		cfg.IsSecureFunc = cfg.defaultIsSecureFunc
 	}
 
+	if cfg.SessionCodec == "" && cfg.EncodeFunc == nil {
+		cfg.SessionCodec = defaultSessionCodec
+	}
+
 	if cfg.EncodeFunc == nil {
 		cfg.EncodeFunc = Base64Encode
 	}
@@ -52,92 +61,183 @@ func New(cfg Config) *Session {
 		cfg.Logger = defaultLogger
 	}
 
+	if cfg.MiddlewareLoadErrorHandler == nil {
+		cfg.MiddlewareLoadErrorHandler = cfg.defaultMiddlewareLoadErrorHandler
+	}
+
+	if cfg.MiddlewareSaveErrorHandler == nil {
+		cfg.MiddlewareSaveErrorHandler = cfg.defaultMiddlewareSaveErrorHandler
+	}
+
 	session := &Session{
 		config: cfg,
 		cookie: newCookie(),
 		log:    cfg.Logger,
-		storePool: sync.Pool{
-			New: func() interface{} {
-				return NewStore()
-			},
-		},
-		stopGCChan: make(chan struct{}),
+	}
+
+	// Bound to the session config, so they use its final session id settings
+	if session.config.SessionIDValidatorFunc == nil {
+		if session.config.SessionIDGeneratorFunc == nil {
+			session.config.SessionIDValidatorFunc = session.config.defaultSessionIDValidator
+		} else {
+			// The ids of a custom generator could have any format, e.g. UUIDs
+			session.config.SessionIDValidatorFunc = anySessionIDValidator
+		}
+	}
+
+	if session.config.SessionIDGeneratorFunc == nil {
+		session.config.SessionIDGeneratorFunc = session.config.defaultSessionIDGenerator
+	}
+
+	session.extractors = newExtractors(session)
+	session.defaultExtractors = session.extractors
+	session.writeAllExtractors = len(cfg.Extractors) == 0
+
+	if cfg.DefaultExtractor != nil {
+		session.defaultExtractors = []Extractor{bindExtractor(session, cfg.DefaultExtractor)}
 	}
 
 	return session
 }
 
 // SetProvider sets the session provider used by the sessions manager
+//
+// Returns an error if the cookie settings does not meet the requirements of the cookie name prefix,
+// or if the session ids does not have the minimum entropy.
+//
+// It could be called while the sessions manager is in use to replace the current provider.
+// The GC of the replaced provider is stopped, but the provider is not closed,
+// since the in-flight requests could be still using it
 func (s *Session) SetProvider(provider Provider) error {
-	s.provider = provider
+	if err := s.config.validateCookie(); err != nil {
+		return err
+	}
 
-	if s.provider.NeedGC() {
-		go s.startGC()
+	if err := s.config.validateSessionID(); err != nil {
+		return err
+	}
+
+	if err := s.config.validateSessionCodec(); err != nil {
+		return err
+	}
+
+	p := newSessionProvider(provider)
+
+	if notifier, ok := provider.(ExpirationNotifier); ok && s.config.Hooks.OnExpire != nil {
+		notifier.SetExpirationHandler(s.onProviderExpired)
+	}
+
+	if p.providerCtx.NeedGC() {
+		go s.startGC(p)
+	} else {
+		close(p.gcDone)
+	}
+
+	if old := s.provider.Swap(p); old != nil {
+		old.stopGC()
 	}
 
 	return nil
 }
 
-func (s *Session) startGC() {
+// Close stops the GC and closes the provider if it implements io.Closer,
+// waiting for the in-flight GC to finish until the given context is done.
+// The context of the in-flight GC is canceled, so it stops as soon as the provider supports it
+//
+// The sessions manager could not be used after closing it, until a new provider is set
+func (s *Session) Close(ctx context.Context) error {
+	p := s.provider.Swap(nil)
+	if p == nil {
+		return nil
+	}
+
+	p.stopGC()
+
+	select {
+	case <-p.gcDone:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if closer, ok := p.provider.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (s *Session) startGC(p *sessionProvider) {
+	defer close(p.gcDone)
+
 	ticker := time.NewTicker(s.config.GCLifetime)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			err := s.provider.GC()
+			err := p.providerCtx.GCContext(p.gcCtx)
 			if err != nil {
 				s.log.Printf("session GC crash: %v", err)
 			}
-		case <-s.stopGCChan:
+		case <-p.stopGCChan:
 			return
 		}
 	}
 }
 
-func (s *Session) stopGC() {
-	s.stopGCChan <- struct{}{}
+// providerContext returns a copy of the given context bounded by the ProviderTimeout,
+// which carries the current request
+//
+// Without ProviderTimeout, the given context is not wrapped with a cancelable one,
+// since it would watch the request context in a new goroutine on each call
+func (s *Session) providerContext(c context.Context, ctx *fasthttp.RequestCtx) (context.Context, context.CancelFunc) {
+	c = context.WithValue(c, requestCtxKey{}, ctx)
+
+	if s.config.ProviderTimeout > 0 {
+		return context.WithTimeout(c, s.config.ProviderTimeout)
+	}
+
+	return c, func() {}
+}
+
+// requestContext returns the given request as the context of the provider calls,
+// so they are canceled when the server is shutting down
+//
+// The requests which are not initialized by a fasthttp.Server or RequestCtx.Init (e.g. in tests)
+// have no server, so their Done method could not be called, and the background context is returned instead.
+// They are the only ones without connection id.
+func requestContext(ctx *fasthttp.RequestCtx) context.Context {
+	if ctx == nil || ctx.ConnID() == 0 {
+		return context.Background()
+	}
+
+	return ctx
 }
 
 func (s *Session) setHTTPValues(ctx *fasthttp.RequestCtx, sessionID []byte, expiration time.Duration) {
-	secure := s.config.Secure && s.config.IsSecureFunc(ctx)
-	s.cookie.set(ctx, s.config.CookieName, sessionID, s.config.Domain, expiration, secure, s.config.CookieSameSite)
+	value := s.signSessionID(sessionID)
 
-	if s.config.SessionIDInHTTPHeader {
-		ctx.Request.Header.SetBytesV(s.config.SessionNameInHTTPHeader, sessionID)
-		ctx.Response.Header.SetBytesV(s.config.SessionNameInHTTPHeader, sessionID)
+	for _, extractor := range s.responseExtractors(ctx) {
+		extractor.Set(ctx, value, expiration)
 	}
 }
 
 func (s *Session) delHTTPValues(ctx *fasthttp.RequestCtx) {
-	s.cookie.delete(ctx, s.config.CookieName)
-
-	if s.config.SessionIDInHTTPHeader {
-		ctx.Request.Header.Del(s.config.SessionNameInHTTPHeader)
-		ctx.Response.Header.Del(s.config.SessionNameInHTTPHeader)
+	for _, extractor := range s.responseExtractors(ctx) {
+		extractor.Delete(ctx)
 	}
 }
 
-// Returns the session id from:
-// 1. cookie
-// 2. http headers
-// 3. query string
+// Returns the session id from the first extractor which finds it,
+// which is kept in the request to write back the session id
+//
+// If the signing keys are configured, the values with an invalid signature are ignored,
+// as well as the malformed session ids
 func (s *Session) getSessionID(ctx *fasthttp.RequestCtx) []byte {
-	val := ctx.Request.Header.Cookie(s.config.CookieName)
-	if len(val) > 0 {
-		return val
-	}
-
-	if s.config.SessionIDInHTTPHeader {
-		val = ctx.Request.Header.Peek(s.config.SessionNameInHTTPHeader)
-		if len(val) > 0 {
-			return val
-		}
-	}
+	for _, extractor := range s.extractors {
+		if val := s.verifySessionID(extractor.Extract(ctx)); len(val) > 0 && s.config.SessionIDValidatorFunc(val) {
+			ctx.SetUserValue(extractorUserValueKey{session: s}, extractor)
 
-	if s.config.SessionIDInURLQuery {
-		val = ctx.FormValue(s.config.SessionNameInURLQuery)
-		if len(val) > 0 {
 			return val
 		}
 	}
@@ -147,87 +247,396 @@ func (s *Session) getSessionID(ctx *fasthttp.RequestCtx) []byte {
 
 // Get returns the user session
 // if it does not exist, it will be generated
+//
+// The store is kept in the request until it is saved,
+// so the next calls in the same request return the same store
 func (s *Session) Get(ctx *fasthttp.RequestCtx) (*Store, error) {
-	if s.provider == nil {
+	return s.GetContext(requestContext(ctx), ctx)
+}
+
+// GetContext returns the user session
+// if it does not exist, it will be generated
+//
+// If the stored session could not be decoded, it's destroyed and a new one is generated
+// The provider call is canceled when the given context is done
+// or when the ProviderTimeout is reached
+func (s *Session) GetContext(c context.Context, ctx *fasthttp.RequestCtx) (*Store, error) {
+	p := s.provider.Load()
+	if p == nil {
 		return nil, ErrNotSetProvider
 	}
 
+	if store := s.FromRequestCtx(ctx); store != nil {
+		return store, nil
+	}
+
 	newUser := false
 
 	id := s.getSessionID(ctx)
 	if len(id) == 0 {
-		id = s.config.SessionIDGeneratorFunc()
-		if len(id) == 0 {
-			return nil, ErrEmptySessionID
+		newID, err := s.newSessionID()
+		if err != nil {
+			return nil, err
 		}
 
+		id = newID
+
 		newUser = true
 	}
 
-	store := s.storePool.Get().(*Store)
+	store := NewStore()
 	store.sessionID = id
 	store.defaultExpiration = s.config.Expiration
+	store.isNew = newUser
 
 	if !newUser {
-		data, err := s.provider.Get(id)
+		pctx, cancel := s.providerContext(c, ctx)
+		data, err := p.getData(pctx, store)
+		cancel()
+
+		if errors.Is(err, ErrSessionNotFound) {
+			store.isNew = true
+
+			if s.config.StrictSessionID {
+				// Don't adopt an unknown session id sent by the client
+				newID, err := s.newSessionID()
+				if err != nil {
+					return nil, err
+				}
+
+				store.sessionID = newID
+			}
+
+			err = nil
+		}
+
 		if err != nil {
 			return nil, err
 		}
 
-		if err := s.config.DecodeFunc(&store.data, data); err != nil {
-			return store, nil
+		if err := s.decodeStore(store, data); err != nil {
+			// Handled like a missing session, so the client does not keep an undecodable session,
+			// e.g. encoded with a codec which is not registered anymore
+			s.log.Printf("session decode error: %v", err)
+
+			store = NewStore()
+			store.sessionID = id
+			store.defaultExpiration = s.config.Expiration
+
+			if err := s.renew(c, p, ctx, store); err != nil {
+				return nil, err
+			}
+		} else if s.isLifetimeExpired(store) {
+			runHook(s.config.Hooks.OnExpire, ctx, store.sessionID, store)
+
+			if err := s.renew(c, p, ctx, store); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if store.createdAt.IsZero() {
+		// New session, or stored before recording the creation time
+		store.createdAt = time.Now()
+
+		if !store.isNew && s.config.AbsoluteTimeout > 0 {
+			// Persist the creation time, so the absolute timeout could be enforced
+			store.modified = true
+		}
+	}
+
+	if s.config.FingerprintFunc != nil {
+		if err := s.checkFingerprint(c, p, ctx, store); err != nil {
+			return nil, err
 		}
 	}
 
+	if store.isNew {
+		runHook(s.config.Hooks.OnCreate, ctx, store.sessionID, store)
+	} else {
+		runHook(s.config.Hooks.OnLoad, ctx, store.sessionID, store)
+	}
+
+	s.setRequestCtxStore(ctx, store)
+
 	return store, nil
 }
 
+// getData returns the stored data of the given store session id,
+// keeping its version if the provider supports versioned saves
+func (p *sessionProvider) getData(c context.Context, store *Store) ([]byte, error) {
+	if p.cas == nil {
+		return p.providerCtx.GetContext(c, store.sessionID)
+	}
+
+	data, version, err := p.cas.GetVersion(c, store.sessionID)
+	store.version = version
+
+	return data, err
+}
+
+// encodeStore encodes the store values in an envelope with its metadata
+func (s *Session) encodeStore(store *Store) ([]byte, error) {
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	if s.config.SessionCodec == "" {
+		return s.config.EncodeFunc(store.envelope(time.Now()))
+	}
+
+	codec, ok := LookupSessionCodec(s.config.SessionCodec)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSessionCodec, s.config.SessionCodec)
+	}
+
+	return encodeFormat(codec, store.envelope(time.Now()))
+}
+
+// decodeStore decodes the given data into the store values and attributes
+func (s *Session) decodeStore(store *Store, data []byte) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	decode := s.config.DecodeFunc
+	if hasFormatHeader(data) {
+		decode = decodeFormat
+	}
+
+	if err := decode(&store.data, data); err != nil {
+		return err
+	}
+
+	store.openEnvelope()
+
+	return nil
+}
+
+// compareAndSave saves the store only if it has not been saved by another request since it was loaded,
+// otherwise the MergeFunc is called with the current stored session and the save is retried
+//
+// Returns ErrConcurrentModification if there is no MergeFunc, if it fails wrapping its error,
+// or if the session is still saved concurrently after maxMergeAttempts merges
+func (s *Session) compareAndSave(c context.Context, p *sessionProvider, ctx *fasthttp.RequestCtx, store *Store, expiration time.Duration) error {
+	id := store.GetSessionID()
+
+	for attempt := 0; ; attempt++ {
+		data, err := s.encodeStore(store)
+		if err != nil {
+			return err
+		}
+
+		version, err := p.cas.CompareAndSave(c, id, data, store.Version(), expiration)
+		if err == nil {
+			store.setVersion(version)
+
+			return nil
+		}
+
+		if !errors.Is(err, ErrConcurrentModification) || s.config.MergeFunc == nil || attempt >= maxMergeAttempts {
+			return err
+		}
+
+		current := NewStore()
+		current.sessionID = id
+		current.defaultExpiration = store.defaultExpiration
+
+		data, err = p.getData(c, current)
+		if errors.Is(err, ErrSessionNotFound) {
+			current.isNew = true
+		} else if err != nil {
+			return err
+		} else if err := s.decodeStore(current, data); err != nil {
+			return err
+		}
+
+		if err := s.config.MergeFunc(ctx, store, current); err != nil {
+			return fmt.Errorf("%w: %w", ErrConcurrentModification, err)
+		}
+
+		store.setVersion(current.version)
+	}
+}
+
+// isLifetimeExpired checks whether the session has exceeded the AbsoluteTimeout
+func (s *Session) isLifetimeExpired(store *Store) bool {
+	if s.config.AbsoluteTimeout <= 0 || store.createdAt.IsZero() {
+		return false
+	}
+
+	return time.Since(store.createdAt) >= s.config.AbsoluteTimeout
+}
+
+// renew destroys the session of the given store in the provider
+// and starts a new empty one with a new session id
+func (s *Session) renew(c context.Context, p *sessionProvider, ctx *fasthttp.RequestCtx, store *Store) error {
+	pctx, cancel := s.providerContext(c, ctx)
+	defer cancel()
+
+	if err := p.providerCtx.DestroyContext(pctx, store.sessionID); err != nil {
+		return err
+	}
+
+	newID, err := s.newSessionID()
+	if err != nil {
+		return err
+	}
+
+	store.Flush()
+	store.sessionID = newID
+	store.createdAt = time.Now()
+	store.fingerprint = ""
+	store.version = 0
+	store.isNew = true
+	store.modified = false
+
+	return nil
+}
+
 // Save saves the user session
 //
+// If the store has not been modified and the provider implements Toucher,
+// only the session expiration is refreshed instead of rewriting its data
+//
+// If SkipUninitialized is true, the new empty sessions are not saved,
+// and the stored sessions which become empty are destroyed
+//
 // Warning: Don't use the store after exec this function, because, you will lose the after data
 // For avoid it, defer this function in your request handler
+//
+// The store is released after it's saved, so it's empty and Save returns ErrStoreReleased with it.
+// Use Store.Clone to keep a copy of it.
 func (s *Session) Save(ctx *fasthttp.RequestCtx, store *Store) error {
-	if s.provider == nil {
+	return s.SaveContext(requestContext(ctx), ctx, store)
+}
+
+// SaveContext saves the user session
+//
+// If the store has not been modified and the provider implements Toucher,
+// only the session expiration is refreshed instead of rewriting its data
+//
+// If SkipUninitialized is true, the new empty sessions are not saved,
+// and the stored sessions which become empty are destroyed
+//
+// The provider call is canceled when the given context is done
+// or when the ProviderTimeout is reached
+//
+// Warning: Don't use the store after exec this function, because, you will lose the after data
+// For avoid it, defer this function in your request handler
+//
+// The store is released after it's saved, so it's empty and Save returns ErrStoreReleased with it.
+// Use Store.Clone to keep a copy of it.
+func (s *Session) SaveContext(c context.Context, ctx *fasthttp.RequestCtx, store *Store) error {
+	p := s.provider.Load()
+	if p == nil {
 		return ErrNotSetProvider
 	}
 
+	if store.IsReleased() {
+		return ErrStoreReleased
+	}
+
 	id := store.GetSessionID()
 	expiration := store.GetExpiration()
+	isNew := store.isNewSession()
 
 	providerExpiration := expiration
 	if expiration == -1 {
 		providerExpiration = keepAliveExpiration
 	}
 
-	data, err := s.config.EncodeFunc(store.GetAll())
-	if err != nil {
-		return err
+	pctx, cancel := s.providerContext(withKeepAlive(c, expiration), ctx)
+	defer cancel()
+
+	if s.config.SkipUninitialized && store.isEmpty() {
+		return s.discard(pctx, p, ctx, store)
 	}
 
-	if err := s.provider.Save(id, data, providerExpiration); err != nil {
-		return err
+	if !isNew && !store.IsModified() && p.toucher != nil {
+		// Nothing to write, only refresh the expiration
+		if err := p.toucher.TouchContext(pctx, id, providerExpiration); err != nil {
+			return err
+		}
+	} else if p.cas != nil && (s.config.MergeFunc != nil || s.config.RejectConcurrentModification) && (isNew || store.IsModified()) {
+		// Versioned saves are opt-in, otherwise the last write wins
+		if err := s.compareAndSave(pctx, p, ctx, store, providerExpiration); err != nil {
+			return err
+		}
+	} else {
+		data, err := s.encodeStore(store)
+		if err != nil {
+			return err
+		}
+
+		if err := p.providerCtx.SaveContext(pctx, id, data, providerExpiration); err != nil {
+			return err
+		}
+	}
+
+	if userID := store.userToBind(); userID != "" && p.indexer != nil {
+		if err := p.indexer.BindUser(pctx, id, []byte(userID)); err != nil {
+			return err
+		}
 	}
 
 	s.setHTTPValues(ctx, id, expiration)
 
-	store.Reset()
-	s.storePool.Put(store)
+	runHook(s.config.Hooks.OnSave, ctx, id, store)
+
+	if s.FromRequestCtx(ctx) == store {
+		s.delRequestCtxStore(ctx)
+	}
+
+	store.release()
+
+	return nil
+}
+
+// discard releases the given empty store without saving it
+//
+// If it was stored, it's destroyed in the provider and the session id is removed from the client
+func (s *Session) discard(c context.Context, p *sessionProvider, ctx *fasthttp.RequestCtx, store *Store) error {
+	if !store.isNewSession() {
+		id := store.GetSessionID()
+
+		if err := p.providerCtx.DestroyContext(c, id); err != nil {
+			return err
+		}
+
+		runHook(s.config.Hooks.OnDestroy, ctx, id, store)
+
+		s.delHTTPValues(ctx)
+	}
+
+	if s.FromRequestCtx(ctx) == store {
+		s.delRequestCtxStore(ctx)
+	}
+
+	store.release()
 
 	return nil
 }
 
 // Regenerate generates a new session id to the current user
 func (s *Session) Regenerate(ctx *fasthttp.RequestCtx) error {
-	if s.provider == nil {
+	return s.RegenerateContext(requestContext(ctx), ctx)
+}
+
+// RegenerateContext generates a new session id to the current user
+//
+// The provider call is canceled when the given context is done
+// or when the ProviderTimeout is reached
+func (s *Session) RegenerateContext(c context.Context, ctx *fasthttp.RequestCtx) error {
+	p := s.provider.Load()
+	if p == nil {
 		return ErrNotSetProvider
 	}
 
 	id := s.getSessionID(ctx)
 	expiration := s.config.Expiration
 
-	newID := s.config.SessionIDGeneratorFunc()
-	if len(newID) == 0 {
-		return ErrEmptySessionID
+	newID, err := s.newSessionID()
+	if err != nil {
+		return err
 	}
 
 	providerExpiration := expiration
@@ -235,10 +644,79 @@ func (s *Session) Regenerate(ctx *fasthttp.RequestCtx) error {
 		providerExpiration = keepAliveExpiration
 	}
 
-	if err := s.provider.Regenerate(id, newID, providerExpiration); err != nil {
+	pctx, cancel := s.providerContext(withKeepAlive(c, expiration), ctx)
+	defer cancel()
+
+	if err := p.providerCtx.RegenerateContext(pctx, id, newID, providerExpiration); err != nil {
+		return err
+	}
+
+	store := s.FromRequestCtx(ctx)
+	if store != nil {
+		store.setRegeneratedID(newID)
+	}
+
+	// The session id could point to the request cookie, which is updated by setHTTPValues
+	s.runRegenerateHook(ctx, id, newID, store)
+
+	s.setHTTPValues(ctx, newID, expiration)
+
+	return nil
+}
+
+// RegenerateStore generates a new session id to the given store,
+// moving its data in the provider to the new session id
+//
+// Unlike Regenerate, the store keeps being consistent, so it could be saved later,
+// and the store expiration is used instead of the configured one
+func (s *Session) RegenerateStore(ctx *fasthttp.RequestCtx, store *Store) error {
+	return s.RegenerateStoreContext(requestContext(ctx), ctx, store)
+}
+
+// RegenerateStoreContext generates a new session id to the given store,
+// moving its data in the provider to the new session id
+//
+// The provider call is canceled when the given context is done
+// or when the ProviderTimeout is reached
+func (s *Session) RegenerateStoreContext(c context.Context, ctx *fasthttp.RequestCtx, store *Store) error {
+	p := s.provider.Load()
+	if p == nil {
+		return ErrNotSetProvider
+	}
+
+	if store.IsReleased() {
+		return ErrStoreReleased
+	}
+
+	newID, err := s.newSessionID()
+	if err != nil {
 		return err
 	}
 
+	expiration := store.GetExpiration()
+
+	providerExpiration := expiration
+	if expiration == -1 {
+		providerExpiration = keepAliveExpiration
+	}
+
+	// A new session is not stored yet, so there is nothing to move
+	if !store.isNewSession() {
+		pctx, cancel := s.providerContext(withKeepAlive(c, expiration), ctx)
+		defer cancel()
+
+		if err := p.providerCtx.RegenerateContext(pctx, store.GetSessionID(), newID, providerExpiration); err != nil {
+			return err
+		}
+	}
+
+	id := store.GetSessionID()
+
+	// The user index must follow the new session id
+	store.setRegeneratedID(newID)
+
+	s.runRegenerateHook(ctx, id, newID, store)
+
 	s.setHTTPValues(ctx, newID, expiration)
 
 	return nil
@@ -246,7 +724,16 @@ func (s *Session) Regenerate(ctx *fasthttp.RequestCtx) error {
 
 // Destroy destroys the session of the current user
 func (s *Session) Destroy(ctx *fasthttp.RequestCtx) error {
-	if s.provider == nil {
+	return s.DestroyContext(requestContext(ctx), ctx)
+}
+
+// DestroyContext destroys the session of the current user
+//
+// The provider call is canceled when the given context is done
+// or when the ProviderTimeout is reached
+func (s *Session) DestroyContext(c context.Context, ctx *fasthttp.RequestCtx) error {
+	p := s.provider.Load()
+	if p == nil {
 		return ErrNotSetProvider
 	}
 
@@ -255,11 +742,18 @@ func (s *Session) Destroy(ctx *fasthttp.RequestCtx) error {
 		return nil
 	}
 
-	err := s.provider.Destroy(sessionID)
+	pctx, cancel := s.providerContext(c, ctx)
+	defer cancel()
+
+	err := p.providerCtx.DestroyContext(pctx, sessionID)
 	if err != nil {
 		return err
 	}
 
+	runHook(s.config.Hooks.OnDestroy, ctx, sessionID, s.FromRequestCtx(ctx))
+
+	s.delRequestCtxStore(ctx)
+
 	s.delHTTPValues(ctx)
 
 	return nil
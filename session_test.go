@@ -2,10 +2,12 @@ package session
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,24 +20,37 @@ type mockProvider struct {
 	errDestroy    error
 	errRegenerate error
 	errGC         error
+	data          []byte
 	countValue    int
 	needGCValue   bool
 	gcExecuted    bool
+	destroyed     bool
+
+	savedID              []byte
+	regeneratedID        []byte
+	regenerateExpiration time.Duration
 }
 
 func (p *mockProvider) Get(id []byte) ([]byte, error) {
-	return nil, p.errGet
+	return p.data, p.errGet
 }
 
 func (p *mockProvider) Save(id, data []byte, expiration time.Duration) error {
+	p.savedID = id
+
 	return p.errSave
 }
 
 func (p *mockProvider) Destroy(id []byte) error {
+	p.destroyed = true
+
 	return p.errDestroy
 }
 
 func (p *mockProvider) Regenerate(id, newID []byte, expiration time.Duration) error {
+	p.regeneratedID = append([]byte(nil), id...)
+	p.regenerateExpiration = expiration
+
 	return p.errRegenerate
 }
 
@@ -60,8 +75,8 @@ func Test_New(t *testing.T) {
 	}
 	s := New(cfg)
 
-	if s.config.cookieLen != defaultCookieLen {
-		t.Errorf("Session.cookieLen == %d, want %d", s.config.cookieLen, defaultCookieLen)
+	if s.config.SessionIDLength != defaultSessionIDLength {
+		t.Errorf("Session.SessionIDLength == %d, want %d", s.config.SessionIDLength, defaultSessionIDLength)
 	}
 
 	if s.config.CookieName != defaultSessionKeyName {
@@ -88,12 +103,16 @@ func Test_New(t *testing.T) {
 		t.Errorf("Session.IsSecureFunc == %p, want %p", s.config.IsSecureFunc, cfg.defaultIsSecureFunc)
 	}
 
-	if s.cookie == nil {
-		t.Error("Session.cookie is nil")
+	if s.config.MiddlewareLoadErrorHandler == nil {
+		t.Error("Session.MiddlewareLoadErrorHandler is nil")
+	}
+
+	if s.config.MiddlewareSaveErrorHandler == nil {
+		t.Error("Session.MiddlewareSaveErrorHandler is nil")
 	}
 
-	if v := s.storePool.Get().(*Store); v == nil {
-		t.Errorf("Session.storePool returns: %v", v)
+	if s.cookie == nil {
+		t.Error("Session.cookie is nil")
 	}
 }
 
@@ -108,10 +127,13 @@ func TestSession_SetProvider(t *testing.T) {
 	}
 
 	time.Sleep(s.config.GCLifetime + 100*time.Millisecond)
-	s.stopGC()
 
-	if s.provider != provider {
-		t.Errorf("Session.SetProvider() provider == %p, want %p", s.provider, provider)
+	if p := s.provider.Load(); p == nil || p.provider != provider {
+		t.Errorf("Session.SetProvider() provider == %v, want %p", p, provider)
+	}
+
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
 	if !provider.gcExecuted {
@@ -131,12 +153,13 @@ func TestSession_startGC(t *testing.T) {
 		needGCValue: true,
 		errGC:       errors.New("mock error"),
 	}
-	s.provider = provider
+	p := newSessionProvider(provider)
 
-	go s.startGC()
+	go s.startGC(p)
 	time.Sleep(s.config.GCLifetime + 100*time.Millisecond)
 
-	s.stopGC()
+	p.stopGC()
+	<-p.gcDone
 
 	if output.Len() == 0 {
 		t.Errorf("the error it not write on log")
@@ -144,19 +167,185 @@ func TestSession_startGC(t *testing.T) {
 }
 
 func TestSession_stopGC(t *testing.T) {
-	s := New(Config{
-		GCLifetime: 100 * time.Millisecond,
-	})
+	p := newSessionProvider(new(mockProvider))
 
-	go s.stopGC()
+	go p.stopGC()
+	go p.stopGC()
 
 	select {
-	case <-s.stopGCChan:
+	case <-p.stopGCChan:
 	case <-time.After(200 * time.Millisecond):
 		t.Error("Signal for stop GC is not sent")
 	}
 }
 
+type mockCloser struct {
+	mockProvider
+
+	closed   bool
+	errClose error
+}
+
+func (p *mockCloser) Close() error {
+	p.closed = true
+
+	return p.errClose
+}
+
+func TestSession_Close(t *testing.T) {
+	s := New(Config{
+		GCLifetime: 50 * time.Millisecond,
+	})
+	provider := &mockCloser{mockProvider: mockProvider{needGCValue: true}}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p := s.provider.Load()
+
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case <-p.gcDone:
+	default:
+		t.Error("The GC is not stopped")
+	}
+
+	if !provider.closed {
+		t.Error("The provider is not closed")
+	}
+
+	if _, err := s.Get(new(fasthttp.RequestCtx)); err != ErrNotSetProvider {
+		t.Errorf("Session.Get() after close error == %v, want %v", err, ErrNotSetProvider)
+	}
+
+	if err := s.Close(context.Background()); err != nil {
+		t.Errorf("Session.Close() twice error == %v, want %v", err, nil)
+	}
+}
+
+type mockBlockingGC struct {
+	mockProviderContext
+
+	gcStarted chan struct{}
+}
+
+func (p *mockBlockingGC) GCContext(ctx context.Context) error {
+	close(p.gcStarted)
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func TestSession_CloseCancelGC(t *testing.T) {
+	s := New(Config{
+		GCLifetime: 10 * time.Millisecond,
+	})
+	provider := &mockBlockingGC{
+		mockProviderContext: mockProviderContext{mockProvider: mockProvider{needGCValue: true}},
+		gcStarted:           make(chan struct{}),
+	}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	<-provider.gcStarted
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := s.Close(ctx); err != nil {
+		t.Errorf("Session.Close() error == %v, want %v", err, nil)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	if c := requestContext(nil); c != context.Background() {
+		t.Errorf("requestContext(nil) == %v, want the background context", c)
+	}
+
+	if c := requestContext(new(fasthttp.RequestCtx)); c != context.Background() {
+		t.Errorf("requestContext() of a request without server == %v, want the background context", c)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Init(new(fasthttp.Request), nil, nil)
+
+	if c := requestContext(ctx); c != ctx {
+		t.Errorf("requestContext() == %v, want the request", c)
+	}
+}
+
+func TestSession_CloseError(t *testing.T) {
+	s := New(Config{})
+	provider := &mockCloser{errClose: errors.New("close")}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := s.Close(context.Background()); err != provider.errClose {
+		t.Errorf("Session.Close() error == %v, want %v", err, provider.errClose)
+	}
+}
+
+func TestSession_SetProviderReplace(t *testing.T) {
+	s := New(Config{
+		GCLifetime: 50 * time.Millisecond,
+	})
+	oldProvider := &mockCloser{mockProvider: mockProvider{needGCValue: true}}
+
+	if err := s.SetProvider(oldProvider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	old := s.provider.Load()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// Concurrent requests while the provider is replaced
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			ctx := new(fasthttp.RequestCtx)
+
+			if store, err := s.Get(ctx); err == nil {
+				s.Save(ctx, store)
+			}
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		if err := s.SetProvider(&mockProvider{needGCValue: true}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	select {
+	case <-old.gcDone:
+	case <-time.After(time.Second):
+		t.Error("The GC of the replaced provider is not stopped")
+	}
+
+	if oldProvider.closed {
+		t.Error("The replaced provider is closed")
+	}
+
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestSession_setHTTPValues(t *testing.T) {
 	ctx := new(fasthttp.RequestCtx)
 	s := New(Config{
@@ -215,7 +404,7 @@ func TestSession_delHTTPValues(t *testing.T) {
 }
 
 func TestSession_getSessionID(t *testing.T) {
-	id := "123fvd4r43t4j3tn"
+	id := "123fvd4r43t4j3tn123fvd4r43t4j3tn"
 
 	// From cookie
 	s := New(Config{})
@@ -295,7 +484,7 @@ func TestSession_GetProviderError(t *testing.T) {
 	}
 
 	ctx := new(fasthttp.RequestCtx)
-	ctx.Request.Header.SetCookie(s.config.CookieName, "aiasdiasd")
+	ctx.Request.Header.SetCookie(s.config.CookieName, "aiasdiasdaiasdiasdaiasdiasdaiasd")
 
 	store, err := s.Get(ctx)
 	if err != provider.errGet {
@@ -378,6 +567,117 @@ func TestSession_Save(t *testing.T) {
 	if len(store.sessionID) > 0 {
 		t.Error("store is not reseted")
 	}
+
+	if !store.IsReleased() {
+		t.Error("store is not released")
+	}
+
+	if err := s.Save(ctx, store); err != ErrStoreReleased {
+		t.Errorf("Save() of a released store error == %v, want %v", err, ErrStoreReleased)
+	}
+}
+
+func TestSession_SkipUninitialized(t *testing.T) {
+	s := New(Config{SkipUninitialized: true})
+	provider := new(mockProvider)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if provider.savedID != nil {
+		t.Error("A new empty session must not be saved")
+	}
+
+	if ctx.Response.Header.PeekCookie(s.config.CookieName) != nil {
+		t.Error("The session id of a new empty session must not be sent")
+	}
+
+	if FromRequestCtx(ctx) != nil {
+		t.Error("The store is not removed from the request")
+	}
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	store.Set("k", "v")
+	id := string(store.GetSessionID())
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(provider.savedID) != id {
+		t.Errorf("Provider.Save() id == %s, want %s", provider.savedID, id)
+	}
+
+	if ctx.Response.Header.PeekCookie(s.config.CookieName) == nil {
+		t.Error("HTTP values are not setted")
+	}
+}
+
+func TestSession_SkipUninitializedDestroy(t *testing.T) {
+	events := make(map[string][]HookEvent)
+
+	s := New(Config{Hooks: recordHooks(events), SkipUninitialized: true})
+
+	store := NewStore()
+	store.Set("k", "v")
+
+	data, err := s.encodeStore(store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	provider := &mockProvider{data: data}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := "asd2324nasd2324nasd2324nasd2324n"
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	store.Delete("k")
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !provider.destroyed {
+		t.Error("A stored session which becomes empty must be destroyed")
+	}
+
+	if provider.savedID != nil {
+		t.Error("A stored session which becomes empty must not be saved")
+	}
+
+	if len(events["destroy"]) != 1 || string(events["destroy"][0].ID) != id {
+		t.Errorf("OnDestroy events == %v, want one of session %s", events["destroy"], id)
+	}
+
+	if v := string(ctx.Response.Header.PeekCookie(s.config.CookieName)); !strings.Contains(v, "expires=") {
+		t.Errorf("The session cookie is not deleted: %s", v)
+	}
 }
 
 func TestSession_RegenerateErrNotProvider(t *testing.T) {
@@ -401,7 +701,7 @@ func TestSession_RegenerateErrEmptySessionID(t *testing.T) {
 	}
 
 	ctx := new(fasthttp.RequestCtx)
-	ctx.Request.Header.SetCookie(s.config.CookieName, "d32r2f2ecev")
+	ctx.Request.Header.SetCookie(s.config.CookieName, "d32r2f2ecevd32r2f2ecevd32r2f2ece")
 
 	if err := s.Regenerate(ctx); err != ErrEmptySessionID {
 		t.Errorf("Expected error: %v", ErrEmptySessionID)
@@ -417,7 +717,7 @@ func TestSession_RegenerateProviderError(t *testing.T) {
 	}
 
 	ctx := new(fasthttp.RequestCtx)
-	ctx.Request.Header.SetCookie(s.config.CookieName, "d32r2f2ecev")
+	ctx.Request.Header.SetCookie(s.config.CookieName, "d32r2f2ecevd32r2f2ecevd32r2f2ece")
 
 	if err := s.Regenerate(ctx); err != provider.errRegenerate {
 		t.Errorf("Expected error: %v", provider.errRegenerate)
@@ -432,7 +732,7 @@ func TestSession_Regenerate(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	id := "d32r2f2ecev"
+	id := "d32r2f2ecevd32r2f2ecevd32r2f2ece"
 	ctx := new(fasthttp.RequestCtx)
 	ctx.Request.Header.SetCookie(s.config.CookieName, id)
 
@@ -445,6 +745,99 @@ func TestSession_Regenerate(t *testing.T) {
 	}
 }
 
+func TestSession_RegenerateStore(t *testing.T) {
+	s := New(Config{})
+	provider := &mockProvider{}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := "d32r2f2ecevd32r2f2ecevd32r2f2ece"
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expiration := 10 * time.Minute
+	store.SetExpiration(expiration)
+
+	if err := s.RegenerateStore(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	newID := string(store.GetSessionID())
+	if newID == id {
+		t.Error("The store session id is not regenerated")
+	}
+
+	if string(provider.regeneratedID) != id {
+		t.Errorf("Provider.Regenerate() id == %s, want %s", provider.regeneratedID, id)
+	}
+
+	if provider.regenerateExpiration != expiration {
+		t.Errorf("Provider.Regenerate() expiration == %v, want %v", provider.regenerateExpiration, expiration)
+	}
+
+	if v := string(ctx.Response.Header.PeekCookie(s.config.CookieName)); !strings.Contains(v, newID) {
+		t.Errorf("HTTP values are not regenerated: %s", v)
+	}
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(provider.savedID) != newID {
+		t.Errorf("Provider.Save() id == %s, want %s", provider.savedID, newID)
+	}
+
+	// New session, nothing to move in the provider
+	provider.regeneratedID = nil
+
+	ctx = new(fasthttp.RequestCtx)
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	id = string(store.GetSessionID())
+
+	if err := s.RegenerateStore(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(store.GetSessionID()) == id {
+		t.Error("The store session id is not regenerated")
+	}
+
+	if provider.regeneratedID != nil {
+		t.Error("Provider.Regenerate() must not be called for a new session")
+	}
+
+	// Provider error
+	provider.errRegenerate = errors.New("regenerate")
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := s.RegenerateStore(ctx, store); err != provider.errRegenerate {
+		t.Errorf("Expected error: %v", provider.errRegenerate)
+	}
+
+	if string(store.GetSessionID()) != id {
+		t.Error("The store session id must not change on error")
+	}
+}
+
 func TestSession_DestroyErrNotProvider(t *testing.T) {
 	s := New(Config{})
 	ctx := new(fasthttp.RequestCtx)
@@ -478,7 +871,7 @@ func TestSession_DestroyProviderError(t *testing.T) {
 	}
 
 	ctx := new(fasthttp.RequestCtx)
-	ctx.Request.Header.SetCookie(s.config.CookieName, "asd2324n")
+	ctx.Request.Header.SetCookie(s.config.CookieName, "asd2324nasd2324nasd2324nasd2324n")
 
 	if err := s.Destroy(ctx); err != provider.errDestroy {
 		t.Errorf("Expected error: %v", provider.errDestroy)
@@ -494,9 +887,507 @@ func TestSession_Destroy(t *testing.T) {
 	}
 
 	ctx := new(fasthttp.RequestCtx)
-	ctx.Request.Header.SetCookie(s.config.CookieName, "asd2324n")
+	ctx.Request.Header.SetCookie(s.config.CookieName, "asd2324nasd2324nasd2324nasd2324n")
 
 	if err := s.Destroy(ctx); err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
 }
+
+func TestSession_ProviderTimeout(t *testing.T) {
+	s := New(Config{
+		ProviderTimeout: time.Second,
+	})
+	provider := new(mockProviderContext)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, "asd2324nasd2324nasd2324nasd2324n")
+
+	now := time.Now()
+
+	if _, err := s.Get(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !provider.hasDeadline {
+		t.Fatal("The provider context has not deadline")
+	}
+
+	if provider.deadline.Before(now) || provider.deadline.After(now.Add(s.config.ProviderTimeout+time.Second)) {
+		t.Errorf("The provider context deadline == %v, want ~%v", provider.deadline, now.Add(s.config.ProviderTimeout))
+	}
+}
+
+func TestSession_GetContextCanceled(t *testing.T) {
+	s := New(Config{})
+	provider := new(mockProviderContext)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, "asd2324nasd2324nasd2324nasd2324n")
+
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.GetContext(c, ctx); err != context.Canceled {
+		t.Errorf("Session.GetContext() error == %v, want %v", err, context.Canceled)
+	}
+
+	if provider.hasDeadline {
+		t.Error("The provider context has deadline without ProviderTimeout")
+	}
+
+	store := NewStore()
+	store.SetSessionID([]byte("asd2324nasd2324nasd2324nasd2324n"))
+
+	if err := s.SaveContext(c, ctx, store); err != context.Canceled {
+		t.Errorf("Session.SaveContext() error == %v, want %v", err, context.Canceled)
+	}
+
+	if err := s.RegenerateContext(c, ctx); err != context.Canceled {
+		t.Errorf("Session.RegenerateContext() error == %v, want %v", err, context.Canceled)
+	}
+
+	if err := s.DestroyContext(c, ctx); err != context.Canceled {
+		t.Errorf("Session.DestroyContext() error == %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSession_SaveNotModified(t *testing.T) {
+	s := New(Config{})
+	provider := new(mockToucher)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// New session
+	ctx := new(fasthttp.RequestCtx)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !provider.saveExecuted || provider.touchExecuted {
+		t.Error("A new session must be saved")
+	}
+
+	// Not modified session
+	provider.saveExecuted = false
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, "asd2324nasd2324nasd2324nasd2324n")
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if provider.saveExecuted || !provider.touchExecuted {
+		t.Error("A not modified session must be only touched")
+	}
+
+	if ctx.Response.Header.PeekCookie(s.config.CookieName) == nil {
+		t.Error("HTTP values are not setted")
+	}
+
+	// Modified session
+	provider.touchExecuted = false
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, "asd2324nasd2324nasd2324nasd2324n")
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	store.Set("k", "v")
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !provider.saveExecuted || provider.touchExecuted {
+		t.Error("A modified session must be saved")
+	}
+}
+
+func TestSession_GetNotFound(t *testing.T) {
+	s := New(Config{})
+	provider := &mockProvider{errGet: ErrSessionNotFound}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := "asd2324nasd2324nasd2324nasd2324n"
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := store.GetSessionID(); string(v) != id {
+		t.Errorf("Store.GetSessionID() == %s, want %s", v, id)
+	}
+
+	if !store.isNew {
+		t.Error("Store.isNew == false, want true")
+	}
+}
+
+func TestSession_GetStrictSessionID(t *testing.T) {
+	s := New(Config{
+		StrictSessionID: true,
+	})
+	provider := &mockProvider{errGet: ErrSessionNotFound}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := "asd2324nasd2324nasd2324nasd2324n"
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := store.GetSessionID(); len(v) == 0 || string(v) == id {
+		t.Errorf("Store.GetSessionID() == %s, want a new session id", v)
+	}
+
+	if !store.isNew {
+		t.Error("Store.isNew == false, want true")
+	}
+
+	// Existing session
+	provider.errGet = nil
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := store.GetSessionID(); string(v) != id {
+		t.Errorf("Store.GetSessionID() == %s, want %s", v, id)
+	}
+}
+
+func TestSession_GetStrictSessionIDErrEmptySessionID(t *testing.T) {
+	s := New(Config{
+		StrictSessionID: true,
+		SessionIDGeneratorFunc: func() []byte {
+			return []byte("")
+		},
+	})
+
+	if err := s.SetProvider(&mockProvider{errGet: ErrSessionNotFound}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, "asd2324nasd2324nasd2324nasd2324n")
+
+	if _, err := s.Get(ctx); err != ErrEmptySessionID {
+		t.Errorf("Expected error: %v", ErrEmptySessionID)
+	}
+}
+
+func TestSession_AbsoluteTimeout(t *testing.T) {
+	s := New(Config{
+		AbsoluteTimeout: time.Hour,
+	})
+	provider := new(mockProvider)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	encode := func(createdAt time.Time) []byte {
+		store := NewStore()
+		store.Set("k", "v")
+		store.createdAt = createdAt
+
+		data, err := s.encodeStore(store)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return data
+	}
+
+	id := "asd2324nasd2324nasd2324nasd2324n"
+
+	// Session in its lifetime
+	createdAt := time.Now().Add(-time.Minute)
+	provider.data = encode(createdAt)
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := store.GetSessionID(); string(v) != id {
+		t.Errorf("Store.GetSessionID() == %s, want %s", v, id)
+	}
+
+	if v := store.CreatedAt(); !v.Equal(createdAt) {
+		t.Errorf("Store.CreatedAt() == %v, want %v", v, createdAt)
+	}
+
+	if v := len(store.GetAll().KV); v != 1 {
+		t.Errorf("Store values == %d, want %d", v, 1)
+	}
+
+	if store.IsModified() || provider.destroyed {
+		t.Error("A session in its lifetime must be kept unchanged")
+	}
+
+	// Expired session
+	provider.data = encode(time.Now().Add(-2 * time.Hour))
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !provider.destroyed {
+		t.Error("The expired session is not destroyed")
+	}
+
+	if v := store.GetSessionID(); len(v) == 0 || string(v) == id {
+		t.Errorf("Store.GetSessionID() == %s, want a new session id", v)
+	}
+
+	if v := store.Get("k"); v != nil {
+		t.Errorf("Store.Get() == %v, want %v", v, nil)
+	}
+
+	if !store.isNew {
+		t.Error("Store.isNew == false, want true")
+	}
+
+	if v := time.Since(store.CreatedAt()); v > time.Minute {
+		t.Errorf("Store.CreatedAt() is not renewed, elapsed %v", v)
+	}
+
+	// Destroy error
+	provider.errDestroy = errors.New("destroy")
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	if _, err := s.Get(ctx); err != provider.errDestroy {
+		t.Errorf("Session.Get() error == %v, want %v", err, provider.errDestroy)
+	}
+}
+
+func TestSession_SaveConcurrentModification(t *testing.T) {
+	s := New(Config{})
+	provider := new(mockCompareAndSaver)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := "asd2324nasd2324nasd2324nasd2324n"
+
+	get := func() *Store {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+		store, err := s.Get(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return store
+	}
+
+	// Without MergeFunc, the last save wins
+	store := get()
+	store.Set("a", 1)
+
+	if err := s.Save(new(fasthttp.RequestCtx), store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if provider.version != 0 || string(provider.savedID) != id {
+		t.Errorf("Provider version == %d, saved id == %s, want a plain save", provider.version, provider.savedID)
+	}
+
+	// Versioned saves with MergeFunc
+	s.config.MergeFunc = func(ctx *fasthttp.RequestCtx, store, current *Store) error {
+		return ErrConcurrentModification
+	}
+
+	store = get()
+	store.Set("a", 1)
+
+	if err := s.Save(new(fasthttp.RequestCtx), store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if provider.version != 1 {
+		t.Errorf("Provider version == %d, want %d", provider.version, 1)
+	}
+
+	// Parallel requests
+	store1 := get()
+	store2 := get()
+
+	if v := store1.Version(); v != 1 {
+		t.Errorf("Store.Version() == %d, want %d", v, 1)
+	}
+
+	store1.Set("c", 2)
+
+	if err := s.Save(new(fasthttp.RequestCtx), store1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	store2.Set("b", 3)
+
+	if err := s.Save(new(fasthttp.RequestCtx), store2); !errors.Is(err, ErrConcurrentModification) {
+		t.Errorf("Session.Save() error == %v, want %v", err, ErrConcurrentModification)
+	}
+
+	// Merge
+	mergeCalls := 0
+	s.config.MergeFunc = func(ctx *fasthttp.RequestCtx, store, current *Store) error {
+		mergeCalls++
+
+		for k, v := range current.GetAll().KV {
+			if store.Get(k) == nil {
+				store.Set(k, v)
+			}
+		}
+
+		return nil
+	}
+
+	if err := s.Save(new(fasthttp.RequestCtx), store2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if mergeCalls != 1 {
+		t.Errorf("MergeFunc calls == %d, want %d", mergeCalls, 1)
+	}
+
+	store = get()
+
+	if v := store.Get("c"); v != int64(2) {
+		t.Errorf("Store.Get(c) == %v, want %v", v, 2)
+	}
+
+	if v := store.Get("b"); v != int64(3) {
+		t.Errorf("Store.Get(b) == %v, want %v", v, 3)
+	}
+
+	// Merge error
+	errMerge := errors.New("merge")
+	s.config.MergeFunc = func(ctx *fasthttp.RequestCtx, store, current *Store) error {
+		return errMerge
+	}
+
+	store.version = 0
+	store.Set("d", 4)
+
+	if err := s.Save(new(fasthttp.RequestCtx), store); !errors.Is(err, ErrConcurrentModification) || !errors.Is(err, errMerge) {
+		t.Errorf("Session.Save() error == %v, want %v wrapping %v", err, ErrConcurrentModification, errMerge)
+	}
+
+	// Saved concurrently on each merge
+	mergeCalls = 0
+	s.config.MergeFunc = func(ctx *fasthttp.RequestCtx, store, current *Store) error {
+		mergeCalls++
+		provider.version++
+
+		return nil
+	}
+
+	store.version = 0
+
+	if err := s.Save(new(fasthttp.RequestCtx), store); !errors.Is(err, ErrConcurrentModification) {
+		t.Errorf("Session.Save() error == %v, want %v", err, ErrConcurrentModification)
+	}
+
+	if mergeCalls != maxMergeAttempts {
+		t.Errorf("MergeFunc calls == %d, want %d", mergeCalls, maxMergeAttempts)
+	}
+}
+
+func TestSession_RejectConcurrentModification(t *testing.T) {
+	s := New(Config{RejectConcurrentModification: true})
+	provider := new(mockCompareAndSaver)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := "asd2324nasd2324nasd2324nasd2324n"
+
+	get := func() *Store {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+		store, err := s.Get(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return store
+	}
+
+	store1 := get()
+	store2 := get()
+
+	store1.Set("a", 1)
+
+	if err := s.Save(new(fasthttp.RequestCtx), store1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if provider.version != 1 {
+		t.Errorf("Provider version == %d, want %d", provider.version, 1)
+	}
+
+	store2.Set("b", 2)
+
+	if err := s.Save(new(fasthttp.RequestCtx), store2); err != ErrConcurrentModification {
+		t.Errorf("Session.Save() error == %v, want %v", err, ErrConcurrentModification)
+	}
+}
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"crypto/rand"
+	"math"
+	"strings"
+)
+
+// SessionIDEncoding is the alphabet of the session ids
+type SessionIDEncoding int
+
+const (
+	// SessionIDBase62 uses the letters and digits
+	SessionIDBase62 SessionIDEncoding = iota
+
+	// SessionIDBase64URL uses the letters, digits, '-' and '_'
+	SessionIDBase64URL
+
+	// SessionIDHex uses the lowercase hexadecimal digits
+	SessionIDHex
+)
+
+const (
+	base62Alphabet    = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	base64URLAlphabet = base62Alphabet + "-_"
+	hexAlphabet       = "0123456789abcdef"
+)
+
+// alphabet returns the characters of the encoding
+func (e SessionIDEncoding) alphabet() string {
+	switch e {
+	case SessionIDBase64URL:
+		return base64URLAlphabet
+	case SessionIDHex:
+		return hexAlphabet
+	default:
+		return base62Alphabet
+	}
+}
+
+// entropy returns the bits of entropy of a random session id
+// with the given length in this encoding
+func (e SessionIDEncoding) entropy(length int) float64 {
+	return float64(length) * math.Log2(float64(len(e.alphabet())))
+}
+
+// contains returns whether all the characters of the id are in the alphabet of the encoding
+func (e SessionIDEncoding) contains(id []byte) bool {
+	alphabet := e.alphabet()
+
+	for _, c := range id {
+		if strings.IndexByte(alphabet, c) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// randomSessionID returns a session id with the given length
+// of random characters of the alphabet
+func randomSessionID(alphabet string, length int) []byte {
+	// The smallest mask which covers every index of the alphabet,
+	// the random bytes out of the alphabet are discarded so all the characters are equally likely
+	mask := byte(1)
+	for int(mask) < len(alphabet)-1 {
+		mask = mask<<1 | 1
+	}
+
+	id := make([]byte, length)
+	buf := make([]byte, length)
+
+	for i := 0; i < length; {
+		if _, err := rand.Read(buf); err != nil {
+			panic(err)
+		}
+
+		for _, b := range buf {
+			if idx := int(b & mask); idx < len(alphabet) {
+				id[i] = alphabet[idx]
+				i++
+
+				if i == length {
+					break
+				}
+			}
+		}
+	}
+
+	return id
+}
+
+// validateSessionID checks that the length and encoding of the session ids
+// have the minimum entropy
+func (c *Config) validateSessionID() error {
+	if c.SessionIDEncoding.entropy(c.SessionIDLength) < minSessionIDEntropy {
+		return ErrWeakSessionID
+	}
+
+	return nil
+}
+
+// newSessionID returns a new session id from the SessionIDGeneratorFunc
+//
+// Returns ErrEmptySessionID if it's empty, ErrInvalidSessionID if it's not valid,
+// or ErrWeakSessionID if it does not have the minimum entropy
+func (s *Session) newSessionID() ([]byte, error) {
+	id := s.config.SessionIDGeneratorFunc()
+
+	switch {
+	case len(id) == 0:
+		return nil, ErrEmptySessionID
+	case !s.config.SessionIDValidatorFunc(id):
+		return nil, ErrInvalidSessionID
+	case s.config.SessionIDEncoding.entropy(len(id)) < minSessionIDEntropy:
+		return nil, ErrWeakSessionID
+	}
+
+	return id, nil
+}
@@ -0,0 +1,140 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestSessionIDEncoding_entropy(t *testing.T) {
+	testCases := []struct {
+		encoding SessionIDEncoding
+		length   int
+		weak     bool
+	}{
+		{encoding: SessionIDBase62, length: 22},
+		{encoding: SessionIDBase62, length: 21, weak: true},
+		{encoding: SessionIDBase64URL, length: 22},
+		{encoding: SessionIDBase64URL, length: 21, weak: true},
+		{encoding: SessionIDHex, length: 32},
+		{encoding: SessionIDHex, length: 31, weak: true},
+	}
+
+	for _, tc := range testCases {
+		if weak := tc.encoding.entropy(tc.length) < minSessionIDEntropy; weak != tc.weak {
+			t.Errorf("SessionIDEncoding(%d).entropy(%d) weak == %v, want %v", tc.encoding, tc.length, weak, tc.weak)
+		}
+	}
+}
+
+func Test_randomSessionID(t *testing.T) {
+	alphabet := SessionIDHex.alphabet()
+	seen := make(map[byte]bool)
+
+	for i := 0; i < 10; i++ {
+		for _, c := range randomSessionID(alphabet, 64) {
+			seen[c] = true
+		}
+	}
+
+	if len(seen) != len(alphabet) {
+		t.Errorf("randomSessionID() characters == %d, want %d", len(seen), len(alphabet))
+	}
+}
+
+func TestSession_SetProviderWeakSessionID(t *testing.T) {
+	s := New(Config{SessionIDLength: 16, SessionIDEncoding: SessionIDHex})
+
+	if err := s.SetProvider(new(mockProvider)); err != ErrWeakSessionID {
+		t.Errorf("Session.SetProvider() error == %v, want %v", err, ErrWeakSessionID)
+	}
+}
+
+func TestSession_GetMalformedSessionID(t *testing.T) {
+	s := New(Config{})
+
+	// The provider must not be accessed with the malformed session ids
+	if err := s.SetProvider(&mockProvider{errGet: errors.New("error from provider")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	malformedIDs := []string{"short", "asd2324nasd2324nasd2324nasd2:*n", "asd2324nasd2324nasd2324nasd2324nasd"}
+
+	for _, id := range malformedIDs {
+		ctx := new(fasthttp.RequestCtx)
+		ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+		store, err := s.Get(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if v := string(store.GetSessionID()); v == id || !store.isNew {
+			t.Errorf("Session.Get() session id == %s, want a new one", v)
+		}
+	}
+}
+
+func TestSession_GetWeakGeneratedSessionID(t *testing.T) {
+	testCases := []struct {
+		id        string
+		validator func(id []byte) bool
+		err       error
+	}{
+		{id: "abc", validator: func(id []byte) bool { return false }, err: ErrInvalidSessionID},
+		{id: "abc", err: ErrWeakSessionID},
+		{id: "0123456789abcdef", validator: func(id []byte) bool { return true }, err: ErrWeakSessionID},
+	}
+
+	for _, tc := range testCases {
+		id := tc.id
+
+		s := New(Config{
+			SessionIDValidatorFunc: tc.validator,
+			SessionIDGeneratorFunc: func() []byte { return []byte(id) },
+		})
+
+		if err := s.SetProvider(new(mockProvider)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := s.Get(new(fasthttp.RequestCtx)); err != tc.err {
+			t.Errorf("Session.Get() error == %v, want %v", err, tc.err)
+		}
+	}
+}
+
+func TestSession_GetCustomGeneratorSessionID(t *testing.T) {
+	uuids := []string{"3f1c6a2e-8d4b-4c9a-9e2f-7b5d1a0c6e84", "b7e2d9f0-1a3c-4e5b-8f6d-2c4a9e7b1d35"}
+
+	s := New(Config{
+		SessionIDGeneratorFunc: func() []byte { return []byte(uuids[0]) },
+	})
+
+	if err := s.SetProvider(new(mockProvider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	store, err := s.Get(new(fasthttp.RequestCtx))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := string(store.GetSessionID()); v != uuids[0] {
+		t.Errorf("Session.Get() session id == %s, want %s", v, uuids[0])
+	}
+
+	// Sent by the client, with the format of the custom generator
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, uuids[1])
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := string(store.GetSessionID()); v != uuids[1] {
+		t.Errorf("Session.Get() session id == %s, want %s", v, uuids[1])
+	}
+}
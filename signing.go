@@ -0,0 +1,70 @@
+package session
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+const signatureSeparator = '.'
+
+var signatureEncoding = base64.RawURLEncoding
+
+func computeSignature(key, id []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(id)
+
+	return mac.Sum(nil)
+}
+
+// signSessionID returns the session id with its signature appended,
+// using the first signing key
+//
+// If there are not signing keys, the session id is returned as is
+func (s *Session) signSessionID(id []byte) []byte {
+	if len(s.config.SigningKeys) == 0 {
+		return id
+	}
+
+	signature := computeSignature(s.config.SigningKeys[0], id)
+
+	dst := make([]byte, len(id)+1+signatureEncoding.EncodedLen(len(signature)))
+	n := copy(dst, id)
+	dst[n] = signatureSeparator
+	signatureEncoding.Encode(dst[n+1:], signature)
+
+	return dst
+}
+
+// verifySessionID returns the session id of the given signed value
+// if its signature matches with any of the signing keys, otherwise returns nil
+//
+// If there are not signing keys, the value is returned as is
+func (s *Session) verifySessionID(value []byte) []byte {
+	if len(s.config.SigningKeys) == 0 {
+		return value
+	}
+
+	i := bytes.LastIndexByte(value, signatureSeparator)
+	if i <= 0 {
+		return nil
+	}
+
+	id, encodedSignature := value[:i], value[i+1:]
+
+	signature := make([]byte, signatureEncoding.DecodedLen(len(encodedSignature)))
+
+	n, err := signatureEncoding.Decode(signature, encodedSignature)
+	if err != nil || n != sha256.Size {
+		return nil
+	}
+
+	for _, key := range s.config.SigningKeys {
+		if hmac.Equal(signature[:n], computeSignature(key, id)) {
+			return id
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestSession_signVerifySessionID(t *testing.T) {
+	id := []byte("123fvd4r43t4j3tn123fvd4r43t4j3tn")
+
+	s := New(Config{})
+
+	if v := s.signSessionID(id); string(v) != string(id) {
+		t.Errorf("Session.signSessionID() without keys == %s, want %s", v, id)
+	}
+
+	if v := s.verifySessionID(id); string(v) != string(id) {
+		t.Errorf("Session.verifySessionID() without keys == %s, want %s", v, id)
+	}
+
+	s = New(Config{
+		SigningKeys: [][]byte{[]byte("key1")},
+	})
+
+	signed := s.signSessionID(id)
+	if string(signed) == string(id) {
+		t.Fatal("Session.signSessionID() does not sign the session id")
+	}
+
+	if v := s.verifySessionID(signed); string(v) != string(id) {
+		t.Errorf("Session.verifySessionID() == %s, want %s", v, id)
+	}
+
+	invalidValues := []string{
+		"",
+		string(id),
+		string(id) + ".",
+		"." + string(signed[len(id)+1:]),
+		string(id) + "x" + string(signed[len(id):]),
+		string(signed[:len(signed)-1]),
+		string(id) + ".invalid",
+	}
+
+	for _, value := range invalidValues {
+		if v := s.verifySessionID([]byte(value)); v != nil {
+			t.Errorf("Session.verifySessionID(%q) == %s, want %v", value, v, nil)
+		}
+	}
+}
+
+func TestSession_verifySessionIDRotation(t *testing.T) {
+	id := []byte("123fvd4r43t4j3tn123fvd4r43t4j3tn")
+
+	oldSession := New(Config{
+		SigningKeys: [][]byte{[]byte("old")},
+	})
+	signed := oldSession.signSessionID(id)
+
+	s := New(Config{
+		SigningKeys: [][]byte{[]byte("new"), []byte("old")},
+	})
+
+	if v := s.verifySessionID(signed); string(v) != string(id) {
+		t.Errorf("Session.verifySessionID() with a rotated key == %s, want %s", v, id)
+	}
+
+	if v := s.signSessionID(id); string(v) == string(signed) {
+		t.Error("Session.signSessionID() must sign with the first key")
+	}
+
+	s = New(Config{
+		SigningKeys: [][]byte{[]byte("new")},
+	})
+
+	if v := s.verifySessionID(signed); v != nil {
+		t.Errorf("Session.verifySessionID() with a removed key == %s, want %v", v, nil)
+	}
+}
+
+func TestSession_getSessionIDSigned(t *testing.T) {
+	id := []byte("123fvd4r43t4j3tn123fvd4r43t4j3tn")
+
+	s := New(Config{
+		SessionIDInHTTPHeader: true,
+		SessionIDInURLQuery:   true,
+		SigningKeys:           [][]byte{[]byte("key")},
+	})
+	signed := s.signSessionID(id)
+
+	// Forged cookie, header and url query
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookieBytesKV([]byte(s.config.CookieName), id)
+	ctx.Request.Header.SetBytesV(s.config.SessionNameInHTTPHeader, id)
+	ctx.Request.SetRequestURI(fmt.Sprintf("/path?%s=%s", s.config.SessionNameInURLQuery, id))
+
+	if v := s.getSessionID(ctx); v != nil {
+		t.Errorf("Session.getSessionID() == %s, want %v", v, nil)
+	}
+
+	// From cookie
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookieBytesKV([]byte(s.config.CookieName), signed)
+
+	if v := s.getSessionID(ctx); string(v) != string(id) {
+		t.Errorf("Session.getSessionID() cookie == %s, want %s", v, id)
+	}
+
+	// From header
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetBytesV(s.config.SessionNameInHTTPHeader, signed)
+
+	if v := s.getSessionID(ctx); string(v) != string(id) {
+		t.Errorf("Session.getSessionID() header == %s, want %s", v, id)
+	}
+
+	// From url query
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.SetRequestURI(fmt.Sprintf("/path?%s=%s", s.config.SessionNameInURLQuery, signed))
+
+	if v := s.getSessionID(ctx); string(v) != string(id) {
+		t.Errorf("Session.getSessionID() url query == %s, want %s", v, id)
+	}
+}
+
+func TestSession_setHTTPValuesSigned(t *testing.T) {
+	id := []byte("123fvd4r43t4j3tn123fvd4r43t4j3tn")
+
+	s := New(Config{
+		SessionIDInHTTPHeader: true,
+		SigningKeys:           [][]byte{[]byte("key")},
+	})
+	signed := s.signSessionID(id)
+
+	ctx := new(fasthttp.RequestCtx)
+	s.setHTTPValues(ctx, id, 0)
+
+	resultCookie := new(fasthttp.Cookie)
+	resultCookie.SetKey(s.config.CookieName)
+	ctx.Response.Header.Cookie(resultCookie)
+
+	if v := resultCookie.Value(); string(v) != string(signed) {
+		t.Errorf("Response cookie value == %s, want %s", v, signed)
+	}
+
+	if v := ctx.Response.Header.Peek(s.config.SessionNameInHTTPHeader); string(v) != string(signed) {
+		t.Errorf("Response header value == %s, want %s", v, signed)
+	}
+
+	if v := s.getSessionID(ctx); string(v) != string(id) {
+		t.Errorf("Session.getSessionID() == %s, want %s", v, id)
+	}
+}
+
+func TestSession_GetForgedSessionID(t *testing.T) {
+	s := New(Config{
+		SigningKeys: [][]byte{[]byte("key")},
+	})
+	provider := &mockProvider{errGet: errors.New("the provider must not be called")}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	id := "asd2324nasd2324nasd2324nasd2324n"
+
+	ctx := new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := store.GetSessionID(); string(v) == id {
+		t.Error("Session.Get() adopts a forged session id")
+	}
+}
@@ -19,8 +19,36 @@ func NewStore() *Store {
 	}
 }
 
+// isReleased returns whether the store has been released by Session.Save,
+// in the debug builds it panics instead
+//
+// The store lock must be held
+func (s *Store) isReleased() bool {
+	if s.released && debugStore {
+		panic(ErrStoreReleased)
+	}
+
+	return s.released
+}
+
+// IsReleased returns whether the store has been released by Session.Save,
+// so it's empty and it must not be used anymore
+func (s *Store) IsReleased() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.released
+}
+
 // Get returns a value from the given key
 func (s *Store) Get(key string) interface{} {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.isReleased() {
+		return nil
+	}
+
 	return s.data.KV[key]
 }
 
@@ -29,19 +57,100 @@ func (s *Store) GetBytes(key []byte) interface{} {
 	return s.Get(strconv.B2S(key))
 }
 
-// GetAll returns all stored values
+// GetAll returns a copy of all stored values
 func (s *Store) GetAll() Dict {
-	return s.data
+	return s.Snapshot()
+}
+
+// Snapshot returns a copy of all stored values,
+// which is not changed by the later changes of the store
+//
+// The values themselves are not copied, so the values of reference types are shared with the store
+func (s *Store) Snapshot() Dict {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	dst := Dict{KV: make(map[string]interface{}, len(s.data.KV))}
+
+	if s.isReleased() {
+		return dst
+	}
+
+	for k, v := range s.data.KV {
+		dst.KV[k] = v
+	}
+
+	return dst
+}
+
+// Clone returns an independent copy of the store, with the same session id, values and metadata
+//
+// The values themselves are not copied, so the values of reference types are shared with the store
+func (s *Store) Clone() *Store {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.isReleased() {
+		return NewStore()
+	}
+
+	dst := &Store{
+		sessionID:         append([]byte(nil), s.sessionID...),
+		data:              Dict{KV: make(map[string]interface{}, len(s.data.KV))},
+		defaultExpiration: s.defaultExpiration,
+		expiration:        s.expiration,
+		hasExpiration:     s.hasExpiration,
+		createdAt:         s.createdAt,
+		lastAccess:        s.lastAccess,
+		fingerprint:       s.fingerprint,
+		userID:            s.userID,
+		bindUser:          s.bindUser,
+		version:           s.version,
+		isNew:             s.isNew,
+		modified:          s.modified,
+	}
+
+	for k, v := range s.data.KV {
+		dst.data.KV[k] = v
+	}
+
+	if len(s.flashes) > 0 {
+		dst.flashes = make(map[string][]interface{}, len(s.flashes))
+
+		for category, flashes := range s.flashes {
+			dst.flashes[category] = append([]interface{}(nil), flashes...)
+		}
+	}
+
+	return dst
 }
 
 // Ptr returns the internal store pointer
+//
+// The store is marked as modified, since the values could be changed through it.
+// The changes through the pointer are not guarded by the store lock,
+// so it must not be used concurrently with the store.
 func (s *Store) Ptr() *Dict {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.isReleased()
+	s.modified = true
+
 	return &s.data
 }
 
 // Set saves a value for the given key
 func (s *Store) Set(key string, value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isReleased() {
+		return
+	}
+
 	s.data.KV[key] = value
+	s.modified = true
 }
 
 // SetBytes saves a value for the given key
@@ -51,7 +160,19 @@ func (s *Store) SetBytes(key []byte, value interface{}) {
 
 // Delete deletes a value from the given key
 func (s *Store) Delete(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isReleased() {
+		return
+	}
+
+	if _, ok := s.data.KV[key]; !ok {
+		return
+	}
+
 	delete(s.data.KV, key)
+	s.modified = true
 }
 
 // DeleteBytes deletes a value from the given key
@@ -60,53 +181,282 @@ func (s *Store) DeleteBytes(key []byte) {
 }
 
 // Flush removes all stored values
+//
+// The session metadata, like the expiration, is kept
 func (s *Store) Flush() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isReleased() {
+		return
+	}
+
+	s.flush()
+}
+
+// flush removes all stored values
+//
+// The store lock must be held
+func (s *Store) flush() {
 	for k := range s.data.KV {
 		delete(s.data.KV, k)
+		s.modified = true
 	}
 }
 
 // GetSessionID returns the session id
 func (s *Store) GetSessionID() []byte {
 	s.lock.RLock()
-	id := s.sessionID
-	s.lock.RUnlock()
+	defer s.lock.RUnlock()
+
+	if s.isReleased() {
+		return nil
+	}
 
-	return id
+	return s.sessionID
 }
 
 // SetSessionID sets the session id
 func (s *Store) SetSessionID(id []byte) {
 	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isReleased() {
+		return
+	}
+
 	s.sessionID = id
-	s.lock.Unlock()
 }
 
 // HasExpirationChanged checks wether the expiration has been changed
 func (s *Store) HasExpirationChanged() bool {
-	return s.Get(expirationAttrKey) != nil
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.isReleased()
+
+	return s.hasExpiration
 }
 
 // GetExpiration returns the expiration for current session
 func (s *Store) GetExpiration() time.Duration {
-	expiration, ok := s.Get(expirationAttrKey).(int64)
-	if !ok {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.isReleased()
+
+	return s.getExpiration()
+}
+
+// getExpiration returns the expiration for current session
+//
+// The store lock must be held
+func (s *Store) getExpiration() time.Duration {
+	if !s.hasExpiration {
 		return s.defaultExpiration
 	}
 
-	return time.Duration(expiration)
+	return s.expiration
 }
 
 // SetExpiration sets the expiration for current session
+//
+// Returns ErrStoreReleased if the store has been released by Session.Save
 func (s *Store) SetExpiration(expiration time.Duration) error {
-	s.Set(expirationAttrKey, int64(expiration))
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isReleased() {
+		return ErrStoreReleased
+	}
+
+	s.expiration = expiration
+	s.hasExpiration = true
+	s.modified = true
 
 	return nil
 }
 
+// CreatedAt returns the time when the session was created
+func (s *Store) CreatedAt() time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.isReleased()
+
+	return s.createdAt
+}
+
+// LastAccess returns the time when the session was saved for the last time
+//
+// It's zero for the new sessions
+func (s *Store) LastAccess() time.Time {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.isReleased()
+
+	return s.lastAccess
+}
+
+// UserID returns the id of the user which the session is bound to by Session.BindUser
+func (s *Store) UserID() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.isReleased()
+
+	return s.userID
+}
+
+// Version returns the version of the stored session which the store was loaded from
+//
+// It's always 0 if the provider does not support versioned saves
+func (s *Store) Version() uint64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.isReleased()
+
+	return s.version
+}
+
+// Metadata returns the information of the session which is stored along with its values
+func (s *Store) Metadata() Metadata {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.isReleased()
+
+	return Metadata{
+		CreatedAt:  s.createdAt,
+		LastAccess: s.lastAccess,
+		Expiration: s.getExpiration(),
+		UserID:     s.userID,
+		Version:    s.version,
+	}
+}
+
+// isNewSession returns whether the session is not stored yet
+func (s *Store) isNewSession() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.isNew
+}
+
+// setVersion sets the version of the stored session after it has been saved
+func (s *Store) setVersion(version uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.version = version
+}
+
+// setUserID sets the id of the user which the session is bound to,
+// and returns whether the session is new, so it's bound when it's saved
+//
+// Returns ErrStoreReleased if the store has been released by Session.Save
+func (s *Store) setUserID(userID string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isReleased() {
+		return false, ErrStoreReleased
+	}
+
+	s.userID = userID
+	s.modified = true
+
+	if s.isNew {
+		s.bindUser = true
+	}
+
+	return s.isNew, nil
+}
+
+// userToBind returns the id of the user which the session must be bound to when it's saved,
+// or an empty string if it's already bound
+func (s *Store) userToBind() string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if !s.bindUser {
+		return ""
+	}
+
+	return s.userID
+}
+
+// setRegeneratedID sets the new id of a regenerated session,
+// which must be bound again to its user when it's saved
+func (s *Store) setRegeneratedID(id []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isReleased() {
+		return
+	}
+
+	s.sessionID = id
+	s.bindUser = s.userID != ""
+}
+
+// isEmpty returns whether the store has no values nor flash messages
+// and it's not bound to a user
+func (s *Store) isEmpty() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return len(s.data.KV) == 0 && len(s.flashes) == 0 && s.userID == ""
+}
+
+// IsModified checks whether the store values or expiration have been changed
+// since it has been loaded
+func (s *Store) IsModified() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.isReleased()
+
+	return s.modified
+}
+
 // Reset resets the store
 func (s *Store) Reset() {
-	s.Flush()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.reset()
+}
+
+// reset resets the store
+//
+// The store lock must be held
+func (s *Store) reset() {
+	s.flush()
 	s.sessionID = s.sessionID[:0]
 	s.defaultExpiration = 0
+	s.expiration = 0
+	s.hasExpiration = false
+	s.createdAt = time.Time{}
+	s.lastAccess = time.Time{}
+	s.fingerprint = ""
+	s.userID = ""
+	s.flashes = nil
+	s.bindUser = false
+	s.version = 0
+	s.isNew = false
+	s.modified = false
+}
+
+// release resets the store and marks it as released,
+// so it could not be used anymore after it has been saved
+func (s *Store) release() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.reset()
+	s.sessionID = nil
+	s.released = true
 }
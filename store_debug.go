@@ -0,0 +1,7 @@
+//go:build sessiondebug
+
+package session
+
+// debugStore makes the stores panic when they are used after being released,
+// it's enabled by the sessiondebug build tag
+const debugStore = true
@@ -0,0 +1,18 @@
+//go:build sessiondebug
+
+package session
+
+import "testing"
+
+func TestStore_releaseDebug(t *testing.T) {
+	store := NewStore()
+	store.release()
+
+	defer func() {
+		if err := recover(); err != ErrStoreReleased {
+			t.Errorf("Store.Get() after release panics with %v, want %v", err, ErrStoreReleased)
+		}
+	}()
+
+	store.Get("k")
+}
@@ -1,6 +1,8 @@
 package session
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
@@ -122,3 +124,158 @@ func TestStore_Reset(t *testing.T) {
 		t.Error("Store is not reseted")
 	}
 }
+
+func TestStore_IsModified(t *testing.T) {
+	store := NewStore()
+
+	if store.IsModified() {
+		t.Error("Store.IsModified() == true, want false")
+	}
+
+	store.Delete("fake")
+
+	if store.IsModified() {
+		t.Error("Store.IsModified() after delete a not stored key == true, want false")
+	}
+
+	store.Flush()
+
+	if store.IsModified() {
+		t.Error("Store.IsModified() after flush an empty store == true, want false")
+	}
+
+	store.Set("k", "v")
+
+	if !store.IsModified() {
+		t.Error("Store.IsModified() after set == false, want true")
+	}
+
+	store.Reset()
+
+	if store.IsModified() {
+		t.Error("Store.IsModified() after reset == true, want false")
+	}
+
+	store.data.KV["k"] = "v"
+	store.Delete("k")
+
+	if !store.IsModified() {
+		t.Error("Store.IsModified() after delete == false, want true")
+	}
+
+	store.Reset()
+	store.data.KV["k"] = "v"
+	store.Flush()
+
+	if !store.IsModified() {
+		t.Error("Store.IsModified() after flush == false, want true")
+	}
+
+	store.Reset()
+
+	if err := store.SetExpiration(10 * time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !store.IsModified() {
+		t.Error("Store.IsModified() after set expiration == false, want true")
+	}
+}
+
+func TestStore_Concurrent(t *testing.T) {
+	store := NewStore()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			key := fmt.Sprintf("k%d", i)
+
+			for j := 0; j < 100; j++ {
+				store.Set(key, j)
+				store.Get(key)
+				store.Snapshot()
+				store.Metadata()
+				store.Delete(key)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestStore_SnapshotClone(t *testing.T) {
+	store := NewStore()
+	store.SetSessionID([]byte("id"))
+	store.Set("k", "v")
+
+	if err := store.SetExpiration(time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	snapshot := store.Snapshot()
+	clone := store.Clone()
+
+	store.Set("k", "v2")
+	store.Set("k2", "v")
+
+	if v := snapshot.KV["k"]; v != "v" || len(snapshot.KV) != 1 {
+		t.Errorf("Store.Snapshot() == %v, want only %s=%s", snapshot.KV, "k", "v")
+	}
+
+	if v := clone.Get("k"); v != "v" || clone.Get("k2") != nil {
+		t.Errorf("Store.Clone() values are changed by the store: %v", clone.GetAll().KV)
+	}
+
+	if v := string(clone.GetSessionID()); v != "id" {
+		t.Errorf("Store.Clone() session id == %s, want %s", v, "id")
+	}
+
+	if v := clone.GetExpiration(); v != time.Minute {
+		t.Errorf("Store.Clone() expiration == %v, want %v", v, time.Minute)
+	}
+}
+
+func TestStore_release(t *testing.T) {
+	if debugStore {
+		t.Skip("The released stores panic in the debug builds")
+	}
+
+	store := NewStore()
+	store.SetSessionID([]byte("id"))
+	store.Set("k", "v")
+
+	clone := store.Clone()
+
+	store.release()
+
+	if !store.IsReleased() {
+		t.Error("Store.IsReleased() == false, want true")
+	}
+
+	if v := store.Get("k"); v != nil {
+		t.Errorf("Store.Get() after release == %v, want %v", v, nil)
+	}
+
+	if v := store.GetSessionID(); v != nil {
+		t.Errorf("Store.GetSessionID() after release == %s, want %v", v, nil)
+	}
+
+	store.Set("k", "v")
+
+	if v := len(store.GetAll().KV); v != 0 {
+		t.Errorf("Store values after release == %d, want %d", v, 0)
+	}
+
+	if err := store.SetExpiration(time.Minute); err != ErrStoreReleased {
+		t.Errorf("Store.SetExpiration() after release error == %v, want %v", err, ErrStoreReleased)
+	}
+
+	if v := clone.Get("k"); v != "v" || clone.IsReleased() {
+		t.Error("The clone is released with the store")
+	}
+}
@@ -0,0 +1,423 @@
+package session
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// GetAs returns the value of the given key converted to T
+//
+// The values are converted as they are decoded by the session codec,
+// e.g. the integers decoded as int64 are converted to int, and the maps to structs.
+//
+// Returns ErrValueNotFound if the key does not exist,
+// or ErrValueType if the value could not be converted to T
+func GetAs[T any](store *Store, key string) (T, error) {
+	var dst T
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	if store.isReleased() {
+		return dst, ErrStoreReleased
+	}
+
+	value, ok := store.data.KV[key]
+	if !ok {
+		return dst, ErrValueNotFound
+	}
+
+	err := convertValue(reflect.ValueOf(&dst).Elem(), value)
+
+	return dst, err
+}
+
+// Put saves the given value for the given key, so it could be read with GetAs
+//
+// The structs are saved as maps of their exported fields, named by their `session` tag or their name,
+// so they are encoded by the session codec like any other value.
+// Returns ErrValueType if the value contains a type which could not be encoded, like a func or a channel
+func (s *Store) Put(key string, value interface{}) error {
+	v, err := sessionValue(reflect.ValueOf(value))
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.isReleased() {
+		return ErrStoreReleased
+	}
+
+	s.data.KV[key] = v
+	s.modified = true
+
+	return nil
+}
+
+// Bind sets the exported fields of the struct pointed by dst
+// with the values of the keys named by their `session` tag or their name
+//
+// The fields without value are kept unchanged.
+// Returns ErrValueType if dst is not a pointer to a struct, or if a value could not be converted
+func (s *Store) Bind(dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T is not a pointer to a struct", ErrValueType, dst)
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.isReleased() {
+		return ErrStoreReleased
+	}
+
+	return bindStruct(rv.Elem(), s.data.KV)
+}
+
+// fieldName returns the key of the given struct field,
+// or an empty string if it's not stored
+func fieldName(field reflect.StructField) string {
+	if field.PkgPath != "" { // Unexported
+		return ""
+	}
+
+	switch tag := field.Tag.Get("session"); tag {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return tag
+	}
+}
+
+// bindStruct sets the fields of the given struct with the values of their keys
+func bindStruct(dst reflect.Value, src map[string]interface{}) error {
+	for i := 0; i < dst.NumField(); i++ {
+		name := fieldName(dst.Type().Field(i))
+		if name == "" {
+			continue
+		}
+
+		value, ok := src[name]
+		if !ok {
+			continue
+		}
+
+		if err := convertValue(dst.Field(i), value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// sessionValue returns the given value with the types supported by the session codecs,
+// the structs are converted to maps of their fields
+func sessionValue(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	if v.Type() == timeType {
+		return v.Interface(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), nil
+
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+
+	case reflect.String:
+		return v.String(), nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			dst := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(dst), v)
+
+			return dst, nil
+		}
+
+		dst := make([]interface{}, v.Len())
+
+		for i := range dst {
+			item, err := sessionValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			dst[i] = item
+		}
+
+		return dst, nil
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%w: %s has not string keys", ErrValueType, v.Type())
+		}
+
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		dst := make(map[string]interface{}, v.Len())
+
+		iter := v.MapRange()
+		for iter.Next() {
+			item, err := sessionValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+
+			dst[iter.Key().String()] = item
+		}
+
+		return dst, nil
+
+	case reflect.Struct:
+		dst := make(map[string]interface{}, v.NumField())
+
+		for i := 0; i < v.NumField(); i++ {
+			name := fieldName(v.Type().Field(i))
+			if name == "" {
+				continue
+			}
+
+			item, err := sessionValue(v.Field(i))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+
+			dst[name] = item
+		}
+
+		return dst, nil
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		return sessionValue(v.Elem())
+
+	default:
+		return nil, fmt.Errorf("%w: %s could not be encoded", ErrValueType, v.Type())
+	}
+}
+
+// convertValue sets the given decoded value into dst, converting it to the type of dst
+func convertValue(dst reflect.Value, value interface{}) error {
+	if value == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	src := reflect.ValueOf(value)
+
+	if src.Type().AssignableTo(dst.Type()) {
+		dst.Set(src)
+		return nil
+	}
+
+	if dst.Type() == timeType {
+		// Encoded as a RFC 3339 string by some codecs, like JSON
+		s, ok := value.(string)
+		if !ok {
+			return valueTypeError(value, dst)
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrValueType, err)
+		}
+
+		dst.Set(reflect.ValueOf(t))
+
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := toInt64(src)
+		if !ok || dst.OverflowInt(n) {
+			return valueTypeError(value, dst)
+		}
+
+		dst.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := toUint64(src)
+		if !ok || dst.OverflowUint(n) {
+			return valueTypeError(value, dst)
+		}
+
+		dst.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		n, ok := toFloat64(src)
+		if !ok || dst.OverflowFloat(n) {
+			return valueTypeError(value, dst)
+		}
+
+		dst.SetFloat(n)
+
+	case reflect.String, reflect.Bool:
+		if b, ok := value.([]byte); ok && dst.Kind() == reflect.String {
+			dst.SetString(string(b))
+			return nil
+		}
+
+		if src.Kind() != dst.Kind() {
+			return valueTypeError(value, dst)
+		}
+
+		dst.Set(src.Convert(dst.Type()))
+
+	case reflect.Slice:
+		if src.Kind() == reflect.String && dst.Type().Elem().Kind() == reflect.Uint8 {
+			dst.SetBytes([]byte(src.String()))
+			return nil
+		}
+
+		if src.Kind() != reflect.Slice && src.Kind() != reflect.Array {
+			return valueTypeError(value, dst)
+		}
+
+		result := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+
+		for i := 0; i < src.Len(); i++ {
+			if err := convertValue(result.Index(i), src.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+
+		dst.Set(result)
+
+	case reflect.Map:
+		if src.Kind() != reflect.Map {
+			return valueTypeError(value, dst)
+		}
+
+		result := reflect.MakeMapWithSize(dst.Type(), src.Len())
+
+		iter := src.MapRange()
+		for iter.Next() {
+			k := reflect.New(dst.Type().Key()).Elem()
+			if err := convertValue(k, iter.Key().Interface()); err != nil {
+				return err
+			}
+
+			v := reflect.New(dst.Type().Elem()).Elem()
+			if err := convertValue(v, iter.Value().Interface()); err != nil {
+				return err
+			}
+
+			result.SetMapIndex(k, v)
+		}
+
+		dst.Set(result)
+
+	case reflect.Struct:
+		// The structs are saved as maps of their fields by Store.Put
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return valueTypeError(value, dst)
+		}
+
+		return bindStruct(dst, fields)
+
+	case reflect.Ptr:
+		result := reflect.New(dst.Type().Elem())
+		if err := convertValue(result.Elem(), value); err != nil {
+			return err
+		}
+
+		dst.Set(result)
+
+	default:
+		return valueTypeError(value, dst)
+	}
+
+	return nil
+}
+
+func valueTypeError(value interface{}, dst reflect.Value) error {
+	return fmt.Errorf("%w: %T to %s", ErrValueType, value, dst.Type())
+}
+
+func toInt64(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return 0, false
+		}
+
+		return int64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, false
+		}
+
+		return int64(f), true
+	default:
+		return 0, false
+	}
+}
+
+func toUint64(v reflect.Value) (uint64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return 0, false
+		}
+
+		return uint64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+			return 0, false
+		}
+
+		return uint64(f), true
+	default:
+		return 0, false
+	}
+}
+
+func toFloat64(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
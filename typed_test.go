@@ -0,0 +1,261 @@
+package session
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type typedTestUser struct {
+	Name  string
+	Age   int
+	Roles []string
+}
+
+// typedTestStore returns a store with the given values after a round trip through the session encoding
+func typedTestStore(t *testing.T, values map[string]interface{}) *Store {
+	t.Helper()
+
+	s := New(Config{})
+
+	store := NewStore()
+	for k, v := range values {
+		store.Set(k, v)
+	}
+
+	data, err := s.encodeStore(store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := NewStore()
+
+	if err := s.decodeStore(result, data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return result
+}
+
+func TestGetAs(t *testing.T) {
+	store := typedTestStore(t, map[string]interface{}{
+		"int":      42,
+		"duration": int64(time.Minute),
+		"float":    1.5,
+		"string":   "foo",
+		"bytes":    []byte("bar"),
+		"slice":    []string{"a", "b"},
+		"map":      map[string]interface{}{"a": 1, "b": 2},
+		"struct":   map[string]interface{}{"Name": "foo", "Age": 30, "Roles": []string{"admin"}},
+	})
+
+	if v, err := GetAs[int](store, "int"); err != nil || v != 42 {
+		t.Errorf("GetAs[int]() == (%v, %v), want (%v, %v)", v, err, 42, nil)
+	}
+
+	if v, err := GetAs[uint8](store, "int"); err != nil || v != 42 {
+		t.Errorf("GetAs[uint8]() == (%v, %v), want (%v, %v)", v, err, 42, nil)
+	}
+
+	if v, err := GetAs[time.Duration](store, "duration"); err != nil || v != time.Minute {
+		t.Errorf("GetAs[time.Duration]() == (%v, %v), want (%v, %v)", v, err, time.Minute, nil)
+	}
+
+	if v, err := GetAs[float32](store, "float"); err != nil || v != 1.5 {
+		t.Errorf("GetAs[float32]() == (%v, %v), want (%v, %v)", v, err, 1.5, nil)
+	}
+
+	if v, err := GetAs[string](store, "string"); err != nil || v != "foo" {
+		t.Errorf("GetAs[string]() == (%v, %v), want (%v, %v)", v, err, "foo", nil)
+	}
+
+	if v, err := GetAs[string](store, "bytes"); err != nil || v != "bar" {
+		t.Errorf("GetAs[string]() of bytes == (%v, %v), want (%v, %v)", v, err, "bar", nil)
+	}
+
+	if v, err := GetAs[[]string](store, "slice"); err != nil || !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Errorf("GetAs[[]string]() == (%v, %v), want (%v, %v)", v, err, []string{"a", "b"}, nil)
+	}
+
+	if v, err := GetAs[map[string]int](store, "map"); err != nil || !reflect.DeepEqual(v, map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("GetAs[map[string]int]() == (%v, %v), want (%v, %v)", v, err, map[string]int{"a": 1, "b": 2}, nil)
+	}
+
+	want := typedTestUser{Name: "foo", Age: 30, Roles: []string{"admin"}}
+
+	if v, err := GetAs[typedTestUser](store, "struct"); err != nil || !reflect.DeepEqual(v, want) {
+		t.Errorf("GetAs[typedTestUser]() == (%v, %v), want (%v, %v)", v, err, want, nil)
+	}
+
+	if v, err := GetAs[*typedTestUser](store, "struct"); err != nil || v == nil || !reflect.DeepEqual(*v, want) {
+		t.Errorf("GetAs[*typedTestUser]() == (%v, %v), want (%v, %v)", v, err, want, nil)
+	}
+}
+
+func TestGetAsErrors(t *testing.T) {
+	store := typedTestStore(t, map[string]interface{}{
+		"negative": -1,
+		"big":      math.MaxInt32 + 1,
+		"float":    1.5,
+		"string":   "foo",
+	})
+
+	if _, err := GetAs[int](store, "missing"); err != ErrValueNotFound {
+		t.Errorf("GetAs[int]() of a missing key error == %v, want %v", err, ErrValueNotFound)
+	}
+
+	testCases := []struct {
+		name string
+		get  func() error
+	}{
+		{name: "negative uint", get: func() error { _, err := GetAs[uint](store, "negative"); return err }},
+		{name: "int32 overflow", get: func() error { _, err := GetAs[int32](store, "big"); return err }},
+		{name: "fractional int", get: func() error { _, err := GetAs[int](store, "float"); return err }},
+		{name: "string to int", get: func() error { _, err := GetAs[int](store, "string"); return err }},
+		{name: "string to struct", get: func() error { _, err := GetAs[typedTestUser](store, "string"); return err }},
+	}
+
+	for _, tc := range testCases {
+		if err := tc.get(); !errors.Is(err, ErrValueType) {
+			t.Errorf("%s error == %v, want %v", tc.name, err, ErrValueType)
+		}
+	}
+}
+
+type typedTestProfile struct {
+	Name      string        `session:"name"`
+	Timeout   time.Duration `session:"timeout"`
+	User      *typedTestUser
+	CreatedAt time.Time
+	Ignored   string `session:"-"`
+	internal  string
+}
+
+func TestStore_PutGetAs(t *testing.T) {
+	createdAt := time.Unix(0, time.Now().UnixNano())
+
+	profile := typedTestProfile{
+		Name:      "foo",
+		Timeout:   time.Minute,
+		User:      &typedTestUser{Name: "bar", Age: 30, Roles: []string{"admin", "user"}},
+		CreatedAt: createdAt,
+		Ignored:   "ignored",
+		internal:  "internal",
+	}
+
+	store := NewStore()
+
+	if err := store.Put("profile", profile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !store.IsModified() {
+		t.Error("Store.IsModified() after put == false, want true")
+	}
+
+	if err := store.Put("invalid", make(chan int)); !errors.Is(err, ErrValueType) {
+		t.Errorf("Store.Put() of a channel error == %v, want %v", err, ErrValueType)
+	}
+
+	if err := store.Put("invalid", map[int]string{1: "a"}); !errors.Is(err, ErrValueType) {
+		t.Errorf("Store.Put() of a map without string keys error == %v, want %v", err, ErrValueType)
+	}
+
+	s := New(Config{})
+
+	data, err := s.encodeStore(store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := NewStore()
+
+	if err := s.decodeStore(result, data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := profile
+	want.Ignored = ""
+	want.internal = ""
+
+	v, err := GetAs[typedTestProfile](result, "profile")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !v.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("GetAs[typedTestProfile]().CreatedAt == %v, want %v", v.CreatedAt, want.CreatedAt)
+	}
+
+	v.CreatedAt = want.CreatedAt
+
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("GetAs[typedTestProfile]() == %+v, want %+v", v, want)
+	}
+
+	if _, err := GetAs[int](result, "profile"); !errors.Is(err, ErrValueType) {
+		t.Errorf("GetAs[int]() of a struct error == %v, want %v", err, ErrValueType)
+	}
+}
+
+func TestStore_Bind(t *testing.T) {
+	store := typedTestStore(t, map[string]interface{}{
+		"name":    "foo",
+		"timeout": int64(time.Minute),
+		"User":    map[string]interface{}{"Name": "bar", "Age": 30},
+		"Ignored": "ignored",
+	})
+
+	profile := typedTestProfile{Ignored: "kept", CreatedAt: time.Unix(1, 0)}
+
+	if err := store.Bind(&profile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := typedTestProfile{
+		Name:      "foo",
+		Timeout:   time.Minute,
+		User:      &typedTestUser{Name: "bar", Age: 30},
+		CreatedAt: time.Unix(1, 0),
+		Ignored:   "kept",
+	}
+
+	if !reflect.DeepEqual(profile, want) {
+		t.Errorf("Store.Bind() == %+v, want %+v", profile, want)
+	}
+
+	for _, dst := range []interface{}{profile, new(string), nil} {
+		if err := store.Bind(dst); !errors.Is(err, ErrValueType) {
+			t.Errorf("Store.Bind(%T) error == %v, want %v", dst, err, ErrValueType)
+		}
+	}
+
+	store = typedTestStore(t, map[string]interface{}{"name": 1})
+
+	if err := store.Bind(&profile); !errors.Is(err, ErrValueType) {
+		t.Errorf("Store.Bind() of an invalid value error == %v, want %v", err, ErrValueType)
+	}
+}
+
+func TestStore_PutBindReleased(t *testing.T) {
+	if debugStore {
+		t.Skip("released stores panic in debug builds")
+	}
+
+	store := NewStore()
+	store.release()
+
+	if err := store.Put("k", "v"); err != ErrStoreReleased {
+		t.Errorf("Store.Put() error == %v, want %v", err, ErrStoreReleased)
+	}
+
+	if _, err := GetAs[string](store, "k"); err != ErrStoreReleased {
+		t.Errorf("GetAs[string]() error == %v, want %v", err, ErrStoreReleased)
+	}
+
+	if err := store.Bind(new(typedTestUser)); err != ErrStoreReleased {
+		t.Errorf("Store.Bind() error == %v, want %v", err, ErrStoreReleased)
+	}
+}
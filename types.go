@@ -1,7 +1,9 @@
 package session
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/valyala/fasthttp"
@@ -10,11 +12,32 @@ import (
 // Config configuration of session manager
 type Config struct {
 	// cookie name
+	//
+	// The names with the __Secure- prefix require Secure,
+	// and the names with the __Host- prefix require Secure, the "/" CookiePath and no Domain.
 	CookieName string
 
 	// cookie domain
 	Domain string
 
+	// CookiePath is the path of the cookie, "/" by default.
+	//
+	// It allows to run several applications under the same domain with different paths.
+	CookiePath string
+
+	// CookieMaxAge sets the expiration of the cookie with the Max-Age attribute instead of Expires,
+	// so it does not depend on the clock of the client
+	CookieMaxAge bool
+
+	// CookiePartitioned sets the Partitioned attribute of the cookie (CHIPS),
+	// so the cookie is only sent in the context of the top-level site where it's set.
+	// The partitioned cookies are always secure.
+	CookiePartitioned bool
+
+	// CookieDisableHTTPOnly removes the HttpOnly attribute of the cookie,
+	// so it's readable by the client scripts
+	CookieDisableHTTPOnly bool
+
 	// If you want to delete the cookie when the browser closes, set it to -1.
 	//
 	//  0 means no expire, (24 years)
@@ -22,10 +45,25 @@ type Config struct {
 	// >0 is the time.Duration which the session cookies should expire.
 	Expiration time.Duration
 
+	// AbsoluteTimeout is the maximum lifetime of a session since it was created,
+	// regardless of its activity. The expired sessions are destroyed
+	// and replaced by a new one on the next Get.
+	//
+	// 0 means no absolute timeout, the session only expires when it's idle.
+	AbsoluteTimeout time.Duration
+
 	// gc life time to execute it
 	GCLifetime time.Duration
 
+	// ProviderTimeout is the maximum duration of each provider call.
+	//
+	// 0 means no timeout, the provider call is only bounded by the given context.
+	ProviderTimeout time.Duration
+
 	// set whether to pass this bar cookie only through HTTPS
+	//
+	// It's required by the cookie names with the __Secure- and __Host- prefixes,
+	// which are always secure.
 	Secure bool
 
 	// allows you to declare if your cookie should be restricted to a first-party or same-site context.
@@ -44,35 +82,183 @@ type Config struct {
 	// sessionName in http header
 	SessionNameInHTTPHeader string
 
+	// Extractors are the places where the session id is read from, in order of precedence.
+	// E.g. CookieExtractor, HeaderExtractor or BearerExtractor.
+	//
+	// The session id is written back only by the extractor which found it in the request,
+	// so each client keeps sending it the same way, and the new session ids by DefaultExtractor.
+	//
+	// If empty, the session id is read from the cookie, and from the http header and the url query
+	// if SessionIDInHTTPHeader and SessionIDInURLQuery are enabled, and it's written back to all of them.
+	Extractors []Extractor
+
+	// DefaultExtractor writes back the session ids which are not found in the request, like the new sessions.
+	// If nil, they are written back by all the Extractors.
+	DefaultExtractor Extractor
+
+	// SkipUninitialized skips saving the new sessions without values, and sending their session id to the client,
+	// so the anonymous requests don't create sessions until a value is set.
+	//
+	// If true, Save also destroys the stored sessions whose values have been removed.
+	// It's false by default, so all the sessions are saved.
+	//
+	// It's the SaveUninitialized=false mode of other session managers, inverted so the zero value
+	// of Config keeps saving all the sessions like the previous versions.
+	SkipUninitialized bool
+
+	// StrictSessionID rejects the session ids which are not found in the provider,
+	// generating a new one instead of adopting the id sent by the client.
+	//
+	// It prevents session fixation attacks, but requires that the provider
+	// returns ErrSessionNotFound for unknown session ids.
+	StrictSessionID bool
+
+	// SigningKeys signs the session ids sent to the client with HMAC-SHA256,
+	// so the forged ids are rejected without accessing the provider.
+	//
+	// The first key is used to sign, and all of them are used to verify,
+	// so the keys could be rotated by adding the new one at the beginning.
+	// If empty, the session ids are not signed.
+	SigningKeys [][]byte
+
+	// FingerprintFunc binds the sessions to the client which created them.
+	// It should return the fingerprint of the client, like UserAgentFingerprint,
+	// IPPrefixFingerprint or a combination of them with CombineFingerprints.
+	//
+	// The hash of the fingerprint is recorded when the session is created,
+	// and compared with the current client on each Get. If nil, the sessions are not bound.
+	FingerprintFunc func(*fasthttp.RequestCtx) []byte
+
+	// FingerprintPolicy is the action taken when the fingerprint does not match.
+	//
+	// By default, the session is kept (FingerprintAllow).
+	FingerprintPolicy FingerprintPolicy
+
+	// SessionIDLength is the number of characters of the session ids, 32 by default.
+	//
+	// It must have at least 128 bits of entropy with the SessionIDEncoding,
+	// e.g. 22 characters in base62 or base64url, and 32 in hex.
+	SessionIDLength int
+
+	// SessionIDEncoding is the alphabet of the session ids, SessionIDBase62 by default.
+	SessionIDEncoding SessionIDEncoding
+
 	// SessionIDGeneratorFunc should returns a random session id.
+	//
+	// The generated ids must be valid according to SessionIDValidatorFunc,
+	// and have at least 128 bits of entropy with the SessionIDEncoding.
 	SessionIDGeneratorFunc func() []byte
 
+	// SessionIDValidatorFunc should return whether the session id sent by the client is well-formed.
+	// The malformed session ids are ignored before any access to the provider.
+	//
+	// By default, the session id must have SessionIDLength characters of the SessionIDEncoding,
+	// or any session id is accepted if a custom SessionIDGeneratorFunc is set.
+	SessionIDValidatorFunc func(id []byte) bool
+
 	// IsSecureFunc should return whether the communication channel is secure
 	// in order to set the secure flag to true according to Secure flag.
 	IsSecureFunc func(*fasthttp.RequestCtx) bool
 
+	// SessionCodec is the name of the registered codec which encodes the session data,
+	// SessionCodecBase64 by default if EncodeFunc is not set.
+	// The data is prefixed with a header which identifies the codec,
+	// so the sessions encoded with another registered codec are still decoded after changing it.
+	SessionCodec string
+
 	// EncodeFunc session value serialize func
+	//
+	// It's only used if SessionCodec is not set, and the data is not prefixed with a header
 	EncodeFunc func(src Dict) ([]byte, error)
 
 	// DecodeFunc session value unSerialize func
+	//
+	// It decodes the data without header, like the sessions encoded before setting SessionCodec
 	DecodeFunc func(dst *Dict, src []byte) error
 
+	// MergeFunc resolves the concurrent modifications of a session,
+	// when the provider supports versioned saves (CompareAndSaver).
+	//
+	// If it's set, the modified sessions are saved only if they have not been saved by another request
+	// since they were loaded, otherwise it's called and must merge the values of the current stored session
+	// into the store to save. Save returns ErrConcurrentModification wrapping its error if it fails,
+	// or if the session is still saved concurrently after a few merges.
+	//
+	// If it's nil, the last save wins unless RejectConcurrentModification is set.
+	MergeFunc func(ctx *fasthttp.RequestCtx, store, current *Store) error
+
+	// RejectConcurrentModification saves the modified sessions only if they have not been saved
+	// by another request since they were loaded, when the provider supports versioned saves (CompareAndSaver),
+	// otherwise Save returns ErrConcurrentModification without calling any MergeFunc.
+	RejectConcurrentModification bool
+
 	// Logger
 	Logger Logger
 
-	// value cookie length
-	cookieLen uint32
+	// Hooks are called on the lifecycle events of the sessions
+	Hooks Hooks
+
+	// MiddlewareSkipper should return true to bypass the session middleware for the given request,
+	// so the handler is called without loading nor saving the session.
+	MiddlewareSkipper func(*fasthttp.RequestCtx) bool
+
+	// MiddlewareLoadErrorHandler is called by the session middleware when the session could not be loaded.
+	// The handler is not called after it.
+	//
+	// By default, the error is logged and the response is an internal server error.
+	MiddlewareLoadErrorHandler func(*fasthttp.RequestCtx, error)
+
+	// MiddlewareSaveErrorHandler is called by the session middleware when the session could not be saved.
+	//
+	// By default, the error is logged and the response is an internal server error.
+	MiddlewareSaveErrorHandler func(*fasthttp.RequestCtx, error)
+
+	// MiddlewareLockTTL makes the session middleware hold the lock of the session during the request,
+	// so the requests of the same session are serialized. The lock is released after saving the session,
+	// or when the TTL expires. It requires a provider which implements Locker.
+	//
+	// 0 means that the middleware does not lock the session.
+	MiddlewareLockTTL time.Duration
+
+	// LockWaitTimeout is the maximum duration that Lock waits for a lock held by another request.
+	//
+	// 0 means that Lock does not wait, it returns ErrSessionLocked instead.
+	LockWaitTimeout time.Duration
 }
 
 // Session manages the users sessions
 type Session struct {
-	provider Provider
-	config   Config
-	cookie   *cookie
-	log      Logger
+	provider   atomic.Pointer[sessionProvider]
+	config     Config
+	cookie     *cookie
+	extractors []Extractor
+
+	// defaultExtractors write back the session ids which are not found in the request
+	defaultExtractors []Extractor
+
+	// writeAllExtractors writes back the session id by all the extractors,
+	// whichever found it, like the legacy settings
+	writeAllExtractors bool
+	log                Logger
+}
+
+// sessionProvider is the provider used by the sessions manager with its optional capabilities,
+// which is replaced as a whole by SetProvider
+type sessionProvider struct {
+	provider    Provider
+	providerCtx ProviderContext
+	cas         CompareAndSaver
+	locker      Locker
+	indexer     UserIndexer
+	scanner     Scanner
+	checker     HealthChecker
+	toucher     ToucherContext
 
-	storePool  sync.Pool
 	stopGCChan chan struct{}
+	stopGCOnce sync.Once
+	gcCtx      context.Context
+	gcCancel   context.CancelFunc
+	gcDone     chan struct{}
 }
 
 // Store represents the user session
@@ -80,17 +266,61 @@ type Store struct {
 	sessionID         []byte
 	data              Dict
 	defaultExpiration time.Duration
+	expiration        time.Duration
+	hasExpiration     bool
+	createdAt         time.Time
+	lastAccess        time.Time
+	fingerprint       string
+	userID            string
+	flashes           map[string][]interface{}
+	bindUser          bool
+	version           uint64
+	isNew             bool
+	modified          bool
+	released          bool
 	lock              sync.RWMutex
 }
 
+// Metadata is the information of a session which is stored along with its values
+type Metadata struct {
+	// CreatedAt is the time when the session was created
+	CreatedAt time.Time
+
+	// LastAccess is the time when the session was saved for the last time,
+	// zero if it's a new session
+	LastAccess time.Time
+
+	// Expiration is the expiration of the session
+	Expiration time.Duration
+
+	// UserID is the id of the user which the session is bound to
+	UserID string
+
+	// Version is the version of the stored session, if the provider supports versioned saves
+	Version uint64
+}
+
 type cookie struct{}
 
+// cookieAttrs are the attributes of the session cookie
+type cookieAttrs struct {
+	domain      string
+	path        string
+	sameSite    fasthttp.CookieSameSite
+	secure      bool
+	httpOnly    bool
+	partitioned bool
+	maxAge      bool
+}
+
 type Logger interface {
 	Print(v ...interface{})
 	Printf(format string, v ...interface{})
 }
 
 // Provider interface implemented by providers
+//
+// Get should return ErrSessionNotFound if the given session id does not exist
 type Provider interface {
 	Get(id []byte) ([]byte, error)
 	Save(id, data []byte, expiration time.Duration) error
@@ -100,3 +330,110 @@ type Provider interface {
 	NeedGC() bool
 	GC() error
 }
+
+// ProviderContext interface implemented by context-aware providers
+//
+// The providers which only implement Provider are wrapped by an adapter
+// that checks the context before each call
+type ProviderContext interface {
+	GetContext(ctx context.Context, id []byte) ([]byte, error)
+	SaveContext(ctx context.Context, id, data []byte, expiration time.Duration) error
+	DestroyContext(ctx context.Context, id []byte) error
+	RegenerateContext(ctx context.Context, id, newID []byte, expiration time.Duration) error
+	CountContext(ctx context.Context) int
+	NeedGC() bool
+	GCContext(ctx context.Context) error
+}
+
+// Toucher interface implemented by providers which could refresh
+// the expiration of a session without rewriting its data
+type Toucher interface {
+	Touch(id []byte, expiration time.Duration) error
+}
+
+// ToucherContext interface implemented by context-aware providers which could refresh
+// the expiration of a session without rewriting its data
+type ToucherContext interface {
+	TouchContext(ctx context.Context, id []byte, expiration time.Duration) error
+}
+
+// CompareAndSaver interface implemented by providers which support versioned saves,
+// so the concurrent modifications of a session are detected instead of being overwritten
+//
+// The version 0 means that the session is not stored
+type CompareAndSaver interface {
+	// GetVersion returns the data and the version of the given session id
+	//
+	// Returns ErrSessionNotFound if the session does not exist
+	GetVersion(ctx context.Context, id []byte) ([]byte, uint64, error)
+
+	// CompareAndSave saves the session data and expiration from the given session id
+	// only if its stored version is the given one, and returns the new version
+	//
+	// Returns ErrConcurrentModification if the stored version is another one
+	CompareAndSave(ctx context.Context, id, data []byte, version uint64, expiration time.Duration) (uint64, error)
+}
+
+// Locker interface implemented by providers which could lock a session across instances
+type Locker interface {
+	// Lock acquires the lock of the given session id for the given ttl,
+	// and returns a fencing token which is greater each time the lock is acquired
+	//
+	// Returns ErrSessionLocked if the lock is held by another owner
+	Lock(ctx context.Context, id []byte, ttl time.Duration) (uint64, error)
+
+	// Unlock releases the lock of the given session id if it's still held with the given token
+	Unlock(ctx context.Context, id []byte, token uint64) error
+}
+
+// UserIndexer interface implemented by providers which could index the sessions by user
+type UserIndexer interface {
+	// BindUser adds the given session id to the sessions of the given user id
+	BindUser(ctx context.Context, id, userID []byte) error
+
+	// UserSessions returns the ids of the sessions of the given user id,
+	// excluding the expired and destroyed ones
+	UserSessions(ctx context.Context, userID []byte) ([][]byte, error)
+}
+
+// SessionInfo is the information of a stored session returned by Scanner
+type SessionInfo struct {
+	// ID is the session id
+	ID []byte
+
+	// Size is the size of the stored data in bytes
+	Size int
+
+	// LastActive is the last time that the session was saved,
+	// or zero if it's not known by the provider
+	LastActive time.Time
+
+	// ExpiresAt is the time when the session expires, or zero if it never expires
+	ExpiresAt time.Time
+}
+
+// Scanner interface implemented by providers which could enumerate the stored sessions
+type Scanner interface {
+	// Scan returns up to limit sessions from the given cursor, and the cursor of the next ones,
+	// the limit is always greater than 0
+	//
+	// The first sessions are returned with an empty cursor,
+	// and the next cursor is empty when there are no more sessions.
+	// The sessions saved or destroyed while scanning may be returned or not
+	Scan(ctx context.Context, cursor string, limit int) ([]SessionInfo, string, error)
+}
+
+// HealthChecker interface implemented by providers which check their own health,
+// instead of reading an unknown session id (e.g. the providers which store the sessions in the clients)
+type HealthChecker interface {
+	// HealthCheck returns an error if the provider could not serve the sessions
+	HealthCheck(ctx context.Context) error
+}
+
+// ExpirationNotifier interface implemented by providers which could notify
+// the sessions removed because they expired
+type ExpirationNotifier interface {
+	// SetExpirationHandler sets the function called with the id of each expired session,
+	// it's called once by Session.SetProvider before using the provider
+	SetExpirationHandler(handler func(id []byte))
+}
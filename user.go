@@ -0,0 +1,123 @@
+package session
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/valyala/fasthttp"
+)
+
+// BindUser binds the session of the given store to the given user id,
+// so it could be found by ListUserSessions and destroyed by DestroyUserSessions
+func (s *Session) BindUser(ctx *fasthttp.RequestCtx, store *Store, userID []byte) error {
+	return s.BindUserContext(requestContext(ctx), ctx, store, userID)
+}
+
+// BindUserContext binds the session of the given store to the given user id,
+// so it could be found by ListUserSessions and destroyed by DestroyUserSessions
+//
+// If the session is not stored yet, it's bound when the store is saved.
+// The provider call is canceled when the given context is done
+// or when the ProviderTimeout is reached
+func (s *Session) BindUserContext(c context.Context, ctx *fasthttp.RequestCtx, store *Store, userID []byte) error {
+	p := s.provider.Load()
+	if p == nil {
+		return ErrNotSetProvider
+	}
+
+	if store.IsReleased() {
+		return ErrStoreReleased
+	}
+
+	if p.indexer == nil {
+		return ErrUserIndexNotSupported
+	}
+
+	isNew, err := store.setUserID(string(userID))
+	if err != nil || isNew {
+		return err
+	}
+
+	pctx, cancel := s.providerContext(c, ctx)
+	defer cancel()
+
+	return p.indexer.BindUser(pctx, store.GetSessionID(), userID)
+}
+
+// ListUserSessions returns the ids of the sessions of the given user id
+func (s *Session) ListUserSessions(userID []byte) ([][]byte, error) {
+	return s.ListUserSessionsContext(context.Background(), userID)
+}
+
+// ListUserSessionsContext returns the ids of the sessions of the given user id
+//
+// The provider call is canceled when the given context is done
+// or when the ProviderTimeout is reached
+func (s *Session) ListUserSessionsContext(c context.Context, userID []byte) ([][]byte, error) {
+	p := s.provider.Load()
+	if p == nil {
+		return nil, ErrNotSetProvider
+	}
+
+	if p.indexer == nil {
+		return nil, ErrUserIndexNotSupported
+	}
+
+	pctx, cancel := s.providerContext(c, nil)
+	defer cancel()
+
+	return p.indexer.UserSessions(pctx, userID)
+}
+
+// DestroyUserSessions destroys the sessions of the given user id,
+// except the given session id if it's not empty (e.g. the current one)
+func (s *Session) DestroyUserSessions(userID, exceptID []byte) error {
+	return s.DestroyUserSessionsContext(context.Background(), userID, exceptID)
+}
+
+// DestroyUserSessionsContext destroys the sessions of the given user id,
+// except the given session id if it's not empty (e.g. the current one)
+//
+// The provider calls are canceled when the given context is done
+// or when the ProviderTimeout of each one is reached
+func (s *Session) DestroyUserSessionsContext(c context.Context, userID, exceptID []byte) error {
+	p := s.provider.Load()
+	if p == nil {
+		return ErrNotSetProvider
+	}
+
+	if p.indexer == nil {
+		return ErrUserIndexNotSupported
+	}
+
+	pctx, cancel := s.providerContext(c, nil)
+	ids, err := p.indexer.UserSessions(pctx, userID)
+	cancel()
+
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if len(exceptID) > 0 && bytes.Equal(id, exceptID) {
+			continue
+		}
+
+		pctx, cancel := s.providerContext(c, nil)
+		err := p.providerCtx.DestroyContext(pctx, id)
+		cancel()
+
+		if err != nil {
+			return err
+		}
+
+		if s.config.Hooks.OnDestroy != nil {
+			event := newHookEvent(nil, id, nil)
+			event.UserID = string(userID)
+
+			s.config.Hooks.OnDestroy(event)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package session
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestSession_UserIndexNotSupported(t *testing.T) {
+	s := New(Config{})
+
+	if err := s.SetProvider(new(mockProvider)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := s.BindUser(new(fasthttp.RequestCtx), NewStore(), []byte("user")); err != ErrUserIndexNotSupported {
+		t.Errorf("Expected error: %v", ErrUserIndexNotSupported)
+	}
+
+	if _, err := s.ListUserSessions([]byte("user")); err != ErrUserIndexNotSupported {
+		t.Errorf("Expected error: %v", ErrUserIndexNotSupported)
+	}
+
+	if err := s.DestroyUserSessions([]byte("user"), nil); err != ErrUserIndexNotSupported {
+		t.Errorf("Expected error: %v", ErrUserIndexNotSupported)
+	}
+}
+
+func TestSession_BindUser(t *testing.T) {
+	s := New(Config{})
+	provider := new(mockUserIndexer)
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	userID := []byte("user")
+
+	// New session, bound on save
+	ctx := new(fasthttp.RequestCtx)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	newID := string(store.GetSessionID())
+
+	if err := s.BindUser(ctx, store, userID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v := store.UserID(); v != string(userID) {
+		t.Errorf("Store.UserID() == %s, want %s", v, userID)
+	}
+
+	if len(provider.users) > 0 {
+		t.Error("The new session is bound before it's stored")
+	}
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Stored session, bound at once
+	id := "asd2324nasd2324nasd2324nasd2324n"
+
+	ctx = new(fasthttp.RequestCtx)
+	ctx.Request.Header.SetCookie(s.config.CookieName, id)
+
+	store, err = s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := s.BindUser(ctx, store, userID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ids, err := s.ListUserSessions(userID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(ids) != 2 || string(ids[0]) != newID || string(ids[1]) != id {
+		t.Errorf("Session.ListUserSessions() == %s, want [%s %s]", ids, newID, id)
+	}
+
+	// Regenerated session, bound again on save
+	if err := s.RegenerateStore(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	regeneratedID := string(store.GetSessionID())
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ids, err = s.ListUserSessions(userID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(ids) != 3 || string(ids[2]) != regeneratedID {
+		t.Errorf("Session.ListUserSessions() == %s, want the regenerated session id %s", ids, regeneratedID)
+	}
+}
+
+func TestSession_BindUserConcurrentRead(t *testing.T) {
+	s := New(Config{})
+
+	if err := s.SetProvider(new(mockUserIndexer)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := new(fasthttp.RequestCtx)
+
+	store, err := s.Get(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	wg := new(sync.WaitGroup)
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+
+		if err := s.BindUser(ctx, store, []byte("user")); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		store.UserID()
+		store.IsModified()
+	}()
+
+	wg.Wait()
+
+	if err := s.Save(ctx, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestSession_DestroyUserSessions(t *testing.T) {
+	s := New(Config{})
+	provider := &mockUserIndexer{
+		users: map[string][][]byte{
+			"user": {[]byte("id1"), []byte("id2"), []byte("id3")},
+		},
+	}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := s.DestroyUserSessions([]byte("user"), []byte("id2")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(provider.destroyed) != 2 || string(provider.destroyed[0]) != "id1" || string(provider.destroyed[1]) != "id3" {
+		t.Errorf("Destroyed sessions == %s, want [id1 id3]", provider.destroyed)
+	}
+}
+
+func TestSession_DestroyUserSessionsProviderTimeout(t *testing.T) {
+	s := New(Config{ProviderTimeout: 50 * time.Millisecond})
+	provider := &mockUserIndexer{
+		users: map[string][][]byte{
+			"user": {[]byte("id1"), []byte("id2"), []byte("id3")},
+		},
+		destroyDelay: 30 * time.Millisecond,
+	}
+
+	if err := s.SetProvider(provider); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Each destroy has its own timeout
+	if err := s.DestroyUserSessions([]byte("user"), nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(provider.destroyed) != 3 {
+		t.Errorf("Destroyed sessions == %s, want [id1 id2 id3]", provider.destroyed)
+	}
+}